@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+const numReliabilityBuckets = 10
+
+// BackfillRow is a team's simulated odds as of the end of a single game day,
+// alongside whether the team actually made the playoffs.
+type BackfillRow struct {
+	Date             string  `csv:"date"`
+	Team             string  `csv:"team"`
+	Playoffs         float64 `csv:"playoffs"`
+	D1Seed           float64 `csv:"d1"`
+	D2Seed           float64 `csv:"d2"`
+	D3Seed           float64 `csv:"d3"`
+	WC1              float64 `csv:"wc1"`
+	WC2              float64 `csv:"wc2"`
+	PresidentsTrophy float64 `csv:"presidents"`
+	ConferenceLeader float64 `csv:"conference_leader"`
+	LastOverall      float64 `csv:"last_overall"`
+	MadePlayoffs     int     `csv:"made_playoffs"`
+}
+
+type ReliabilityBucket struct {
+	Low          float64
+	High         float64
+	Forecasts    int
+	MeanForecast float64
+	Observed     float64
+}
+
+type BackfillScore struct {
+	Forecasts int
+	Brier     float64
+	Buckets   []ReliabilityBucket
+}
+
+func RunBackfill(runs int) error {
+	seed := time.Now().Unix()
+	rand.Seed(seed)
+	fmt.Printf("using seed %d\n", seed)
+
+	preseasonElos, err := LoadPreseasonElos()
+	if err != nil {
+		return err
+	}
+
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return err
+	}
+
+	gameDaySet := make(map[string]bool)
+	for _, game := range season {
+		if game.Status != "Final" {
+			return fmt.Errorf("season %s is not complete, game %d is %s", currentSeason, game.GamePK, game.Status)
+		}
+		gameDaySet[game.Date] = true
+	}
+	gameDays := []string{}
+	for day := range gameDaySet {
+		gameDays = append(gameDays, day)
+	}
+	sort.Strings(gameDays)
+
+	actualStandings := CalculateStandings(&teams, &season)
+	madePlayoffs := actualStandings.PlayoffTeams()
+
+	// after the last game day nothing is left to simulate and every forecast
+	// is already 0 or 1, which would only flatter the score
+	if len(gameDays) > 0 {
+		gameDays = gameDays[:len(gameDays)-1]
+	}
+
+	rows := []BackfillRow{}
+	for _, day := range gameDays {
+		seasonAsOf := SeasonAsOf(season, day)
+		inputs := SimulationInputs{
+			Elos:   CurrentElos(preseasonElos, seasonAsOf),
+			Season: seasonAsOf,
+			Teams:  teams,
+		}
+		results := SimulatePlayoffOdds(inputs, runs)
+		fmt.Printf("simulated %s\n", day)
+
+		for team, result := range results {
+			row := BackfillRow{
+				Date:             day,
+				Team:             team,
+				Playoffs:         result.MadePlayoffs / float64(runs),
+				D1Seed:           result.D1Seed / float64(runs),
+				D2Seed:           result.D2Seed / float64(runs),
+				D3Seed:           result.D3Seed / float64(runs),
+				WC1:              result.WC1 / float64(runs),
+				WC2:              result.WC2 / float64(runs),
+				PresidentsTrophy: result.PresidentsTrophy / float64(runs),
+				ConferenceLeader: result.ConferenceLeader / float64(runs),
+				LastOverall:      result.LastOverall / float64(runs),
+			}
+			if madePlayoffs[team] {
+				row.MadePlayoffs = 1
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Date != rows[j].Date {
+			return rows[i].Date < rows[j].Date
+		}
+		return rows[i].Team < rows[j].Team
+	})
+
+	backfillFile, err := os.OpenFile(fmt.Sprintf("data/%s_backfill.csv", currentSeason), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer backfillFile.Close()
+
+	if err := gocsv.MarshalFile(&rows, backfillFile); err != nil {
+		return err
+	}
+
+	PrintBackfillScore(ScoreBackfill(rows))
+	return nil
+}
+
+// SeasonAsOf returns a copy of the season where only games played on or
+// before the given date are Final; every later game is reset to unplayed.
+func SeasonAsOf(season []NHLGameCSVRow, date string) []NHLGameCSVRow {
+	seasonAsOf := make([]NHLGameCSVRow, len(season))
+	for i, game := range season {
+		if game.Date > date {
+			game = NHLGameCSVRow{
+				GamePK:   game.GamePK,
+				Date:     game.Date,
+				Venue:    game.Venue,
+				Status:   "Preview",
+				HomeTeam: game.HomeTeam,
+				AwayTeam: game.AwayTeam,
+			}
+		}
+		seasonAsOf[i] = game
+	}
+	return seasonAsOf
+}
+
+// ScoreBackfill computes the Brier score of the playoff qualification
+// forecasts and buckets them by forecast probability to check calibration.
+func ScoreBackfill(rows []BackfillRow) BackfillScore {
+	score := BackfillScore{Forecasts: len(rows)}
+	for i := 0; i < numReliabilityBuckets; i++ {
+		score.Buckets = append(score.Buckets, ReliabilityBucket{
+			Low:  float64(i) / numReliabilityBuckets,
+			High: float64(i+1) / numReliabilityBuckets,
+		})
+	}
+
+	for _, row := range rows {
+		outcome := float64(row.MadePlayoffs)
+		score.Brier += (row.Playoffs - outcome) * (row.Playoffs - outcome)
+
+		i := int(row.Playoffs * numReliabilityBuckets)
+		if i == numReliabilityBuckets {
+			i -= 1
+		}
+		bucket := &score.Buckets[i]
+		bucket.Forecasts += 1
+		bucket.MeanForecast += row.Playoffs
+		bucket.Observed += outcome
+	}
+
+	if len(rows) > 0 {
+		score.Brier /= float64(len(rows))
+	}
+	for i := range score.Buckets {
+		bucket := &score.Buckets[i]
+		if bucket.Forecasts > 0 {
+			bucket.MeanForecast /= float64(bucket.Forecasts)
+			bucket.Observed /= float64(bucket.Forecasts)
+		}
+	}
+	return score
+}
+
+func PrintBackfillScore(score BackfillScore) {
+	fmt.Printf("playoff brier score: %f over %d forecasts\n", score.Brier, score.Forecasts)
+	fmt.Print("reliability:\n")
+	for _, bucket := range score.Buckets {
+		if bucket.Forecasts == 0 {
+			continue
+		}
+		fmt.Printf("  %3.0f%%-%3.0f%%: %6d forecasts, %6.2f%% forecast, %6.2f%% observed\n", bucket.Low*100, bucket.High*100, bucket.Forecasts, bucket.MeanForecast*100, bucket.Observed*100)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeasonAsOf(t *testing.T) {
+	season := []NHLGameCSVRow{
+		{GamePK: 1, Date: "2023-01-01", Venue: "Arena", Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 2, IsOT: 1, HomeELOPre: 1500, HomeELOPost: 1505, AwayELOPre: 1500, AwayELOPost: 1495},
+		{GamePK: 2, Date: "2023-01-02", Venue: "Arena", Status: "Final", HomeTeam: "A1", AwayTeam: "M1", HomeScore: 4, AwayScore: 1, HomeELOPre: 1505, HomeELOPost: 1512},
+		{GamePK: 3, Date: "2023-01-03", Venue: "Rink", Status: "Final", HomeTeam: "M1", AwayTeam: "A2", HomeScore: 1, AwayScore: 2, IsOT: 1, IsShootout: 1, HomeELOPost: 1490, Quality: 50, Importance: 40, Overall: 45},
+	}
+	seasonAsOf := SeasonAsOf(season, "2023-01-02")
+
+	for i := 0; i < 2; i++ {
+		if seasonAsOf[i] != season[i] {
+			t.Errorf("game %d played by the date changed to %+v", season[i].GamePK, seasonAsOf[i])
+		}
+	}
+	want := NHLGameCSVRow{GamePK: 3, Date: "2023-01-03", Venue: "Rink", Status: "Preview", HomeTeam: "M1", AwayTeam: "A2"}
+	if seasonAsOf[2] != want {
+		t.Errorf("later game %+v, want %+v with its result stripped", seasonAsOf[2], want)
+	}
+	if season[2].Status != "Final" || season[2].HomeELOPost != 1490 {
+		t.Errorf("original season changed to %+v", season[2])
+	}
+	// the elos carried into the simulation stop at the date too
+	elos := CurrentElos(map[string]float64{"A1": 1500, "A2": 1500, "M1": 1500}, seasonAsOf)
+	if elos["M1"] != 1500 || elos["A1"] != 1512 {
+		t.Errorf("elos as of the date %v", elos)
+	}
+}
+
+func TestScoreBackfill(t *testing.T) {
+	rows := []BackfillRow{
+		{Playoffs: 0.95, MadePlayoffs: 1},
+		{Playoffs: 0.85, MadePlayoffs: 0},
+		{Playoffs: 1, MadePlayoffs: 1},
+		{Playoffs: 0.15, MadePlayoffs: 0},
+		{Playoffs: 0, MadePlayoffs: 1},
+	}
+	score := ScoreBackfill(rows)
+
+	if score.Forecasts != len(rows) {
+		t.Errorf("%d forecasts, want %d", score.Forecasts, len(rows))
+	}
+	wantBrier := (0.05*0.05 + 0.85*0.85 + 0 + 0.15*0.15 + 1) / 5
+	if math.Abs(score.Brier-wantBrier) > testTolerance {
+		t.Errorf("brier score %f, want %f", score.Brier, wantBrier)
+	}
+
+	if len(score.Buckets) != numReliabilityBuckets {
+		t.Fatalf("%d buckets, want %d", len(score.Buckets), numReliabilityBuckets)
+	}
+	tests := []struct {
+		bucket       int
+		forecasts    int
+		meanForecast float64
+		observed     float64
+	}{
+		{0, 1, 0, 1},
+		{1, 1, 0.15, 0},
+		{5, 0, 0, 0},
+		{8, 1, 0.85, 0},
+		// a forecast of 1 lands in the top bucket, not past it
+		{9, 2, 0.975, 1},
+	}
+	for _, test := range tests {
+		bucket := score.Buckets[test.bucket]
+		if bucket.Forecasts != test.forecasts || math.Abs(bucket.MeanForecast-test.meanForecast) > testTolerance || math.Abs(bucket.Observed-test.observed) > testTolerance {
+			t.Errorf("bucket %d: %+v, want %d forecasts averaging %f with %f observed", test.bucket, bucket, test.forecasts, test.meanForecast, test.observed)
+		}
+	}
+
+	if empty := ScoreBackfill(nil); empty.Brier != 0 || empty.Forecasts != 0 {
+		t.Errorf("empty backfill scored %+v", empty)
+	}
+}
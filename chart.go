@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth       = 960
+	chartHeight      = 540
+	chartMarginLeft  = 60
+	chartMarginRight = 120
+	chartMarginTop   = 40
+	chartMarginBot   = 40
+	chartAnnotations = 3
+)
+
+var chartColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// EloPoint is a team's elo after a game, along with how much that game moved it.
+type EloPoint struct {
+	Date     time.Time
+	Elo      float64
+	Swing    float64
+	GamePK   int64
+	Opponent string
+}
+
+type EloSeries struct {
+	Team   string
+	Points []EloPoint
+}
+
+// EloTrajectories builds every team's elo over the season from the post-game
+// elos of Final games, starting from the preseason elo the day before the
+// team's first game.
+func EloTrajectories(preseasonElos map[string]float64, season []NHLGameCSVRow) (map[string][]EloPoint, error) {
+	games := []NHLGameCSVRow{}
+	for _, game := range season {
+		if game.Status == "Final" && game.HomeELOPost > 0 && game.AwayELOPost > 0 {
+			games = append(games, game)
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		if games[i].Date != games[j].Date {
+			return games[i].Date < games[j].Date
+		}
+		return games[i].GamePK < games[j].GamePK
+	})
+
+	trajectories := make(map[string][]EloPoint)
+	addPoint := func(team string, date time.Time, pre float64, post float64, gamePK int64, opponent string) {
+		if _, ok := trajectories[team]; !ok {
+			start := pre
+			if elo, ok := preseasonElos[team]; ok {
+				start = elo
+			}
+			trajectories[team] = []EloPoint{{Date: date.AddDate(0, 0, -1), Elo: start}}
+		}
+		trajectories[team] = append(trajectories[team], EloPoint{
+			Date:     date,
+			Elo:      post,
+			Swing:    post - pre,
+			GamePK:   gamePK,
+			Opponent: opponent,
+		})
+	}
+
+	for _, game := range games {
+		date, err := time.Parse("2006-01-02", game.Date)
+		if err != nil {
+			return nil, err
+		}
+		addPoint(game.HomeTeam, date, game.HomeELOPre, game.HomeELOPost, game.GamePK, game.AwayTeam)
+		addPoint(game.AwayTeam, date, game.AwayELOPre, game.AwayELOPost, game.GamePK, game.HomeTeam)
+	}
+	return trajectories, nil
+}
+
+func WriteEloCharts(outDir string) error {
+	preseasonElos, err := LoadPreseasonElos()
+	if err != nil {
+		return err
+	}
+
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return err
+	}
+
+	trajectories, err := EloTrajectories(preseasonElos, season)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	divisions := make(map[string][]EloSeries)
+	for team, points := range trajectories {
+		series := EloSeries{Team: team, Points: points}
+		svg := RenderEloChart(fmt.Sprintf("%s elo rating", team), []EloSeries{series})
+		if err := os.WriteFile(filepath.Join(outDir, fmt.Sprintf("%s.svg", team)), []byte(svg), os.ModePerm); err != nil {
+			return err
+		}
+
+		division := teams[team].Division.Name
+		divisions[division] = append(divisions[division], series)
+	}
+
+	for division, series := range divisions {
+		sort.Slice(series, func(i, j int) bool {
+			return series[i].Team < series[j].Team
+		})
+		svg := RenderEloChart(fmt.Sprintf("%s division elo ratings", division), series)
+		fileName := strings.ToLower(strings.ReplaceAll(division, " ", "_"))
+		if err := os.WriteFile(filepath.Join(outDir, fmt.Sprintf("division_%s.svg", fileName)), []byte(svg), os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("wrote %d team and %d division charts to %s\n", len(trajectories), len(divisions), outDir)
+	return nil
+}
+
+// RenderEloChart draws one line per series and annotates the biggest single
+// game swings across all of them.
+func RenderEloChart(title string, series []EloSeries) string {
+	var minDate, maxDate time.Time
+	minElo, maxElo := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, point := range s.Points {
+			if minDate.IsZero() || point.Date.Before(minDate) {
+				minDate = point.Date
+			}
+			if point.Date.After(maxDate) {
+				maxDate = point.Date
+			}
+			minElo = math.Min(minElo, point.Elo)
+			maxElo = math.Max(maxElo, point.Elo)
+		}
+	}
+	if math.IsInf(minElo, 0) {
+		minElo, maxElo = 1450, 1550
+	}
+	// pad to the surrounding 25 point gridlines
+	minElo = math.Floor(minElo/25)*25 - 25
+	maxElo = math.Ceil(maxElo/25)*25 + 25
+	days := maxDate.Sub(minDate).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBot)
+	x := func(date time.Time) float64 {
+		return chartMarginLeft + plotWidth*(date.Sub(minDate).Hours()/24)/days
+	}
+	y := func(elo float64) float64 {
+		return chartMarginTop + plotHeight*(maxElo-elo)/(maxElo-minElo)
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&svg, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&svg, `<text x="%d" y="24" font-size="16" font-weight="bold">%s</text>`+"\n", chartMarginLeft, html.EscapeString(title))
+
+	// elo gridlines
+	step := 25.0
+	if maxElo-minElo > 300 {
+		step = 50
+	}
+	for elo := minElo; elo <= maxElo; elo += step {
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", chartMarginLeft, y(elo), chartMarginLeft+plotWidth, y(elo))
+		fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end" fill="#555">%.0f</text>`+"\n", chartMarginLeft-6, y(elo)+4, elo)
+	}
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#999" stroke-dasharray="4 4"/>`+"\n", chartMarginLeft, y(1500), chartMarginLeft+plotWidth, y(1500))
+
+	// month labels
+	if !minDate.IsZero() {
+		month := time.Date(minDate.Year(), minDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for !month.After(maxDate) {
+			if !month.Before(minDate) {
+				fmt.Fprintf(&svg, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#eee"/>`+"\n", x(month), chartMarginTop, x(month), chartMarginTop+plotHeight)
+				fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#555">%s</text>`+"\n", x(month), chartMarginTop+plotHeight+18, month.Format("Jan"))
+			}
+			month = month.AddDate(0, 1, 0)
+		}
+	}
+
+	type annotation struct {
+		color string
+		team  string
+		point EloPoint
+	}
+	annotations := []annotation{}
+
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		if len(s.Points) == 0 {
+			continue
+		}
+		coords := []string{}
+		for _, point := range s.Points {
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", x(point.Date), y(point.Elo)))
+			if point.GamePK != 0 {
+				annotations = append(annotations, annotation{color: color, team: s.Team, point: point})
+			}
+		}
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(coords, " "))
+
+		last := s.Points[len(s.Points)-1]
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" fill="%s">%s %.0f</text>`+"\n", x(last.Date)+6, y(last.Elo)+4, color, html.EscapeString(s.Team), last.Elo)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		return math.Abs(annotations[i].point.Swing) > math.Abs(annotations[j].point.Swing)
+	})
+	if len(annotations) > chartAnnotations {
+		annotations = annotations[:chartAnnotations]
+	}
+	for _, a := range annotations {
+		px, py := x(a.point.Date), y(a.point.Elo)
+		labelY := py - 12
+		if a.point.Swing < 0 {
+			labelY = py + 20
+		}
+		fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s"/>`+"\n", px, py, a.color)
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="%s">%s %+.1f vs %s (%s)</text>`+"\n", px, labelY, a.color, html.EscapeString(a.team), a.point.Swing, html.EscapeString(a.point.Opponent), a.point.Date.Format("Jan 2"))
+	}
+
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	seedsPerDivision       = 3
+	wildCardsPerConference = 2
+)
+
+// ClinchRecord is what is known about a team's final standing before the
+// remaining games are played: its current points and regulation wins and the
+// most of each it can still finish with.
+type ClinchRecord struct {
+	Team              string
+	Division          string
+	Conference        string
+	Points            int
+	RegulationWins    int
+	GamesRemaining    int
+	MaxPoints         int
+	MaxRegulationWins int
+}
+
+// ClinchStatus is a team's clinch and elimination status. It is guaranteed
+// but possibly late: each rival is bounded on its own by its points and
+// regulation wins, so it can't see that two rivals still play each other and
+// can't both pass the team, and a team may only be marked a game or two after
+// it has really clinched or been eliminated. Magic numbers are the points a
+// team has to earn itself, in regulation wins, to clinch even if every other
+// team wins out, and tragic numbers the points it can drop before it is
+// eliminated even if every other team loses out; -1 means it is out of the
+// team's hands.
+type ClinchStatus struct {
+	ClinchRecord
+	ClinchedPlayoffs      bool
+	ClinchedDivision      bool
+	ClinchedConference    bool
+	ClinchedPresidents    bool
+	Eliminated            bool
+	EliminatedDivision    bool
+	EliminatedPresidents  bool
+	MagicNumber           int
+	TragicNumber          int
+	DivisionMagicNumber   int
+	PresidentsMagicNumber int
+}
+
+// Mark is the standings marker NHL.com uses for the team's status.
+func (c ClinchStatus) Mark() string {
+	if c.ClinchedPresidents {
+		return "p"
+	}
+	if c.ClinchedConference {
+		return "z"
+	}
+	if c.ClinchedDivision {
+		return "y"
+	}
+	if c.ClinchedPlayoffs {
+		return "x"
+	}
+	if c.Eliminated {
+		return "e"
+	}
+	return ""
+}
+
+// canFinishAhead is whether rival can still finish ahead of team if team
+// earns no more points. Ties on points are broken by regulation wins, and
+// anything past that is assumed to go the rival's way.
+func canFinishAhead(rival ClinchRecord, team ClinchRecord) bool {
+	if rival.MaxPoints != team.Points {
+		return rival.MaxPoints > team.Points
+	}
+	return rival.MaxRegulationWins >= team.RegulationWins
+}
+
+// isSurelyAhead is whether rival finishes ahead of team even if team wins
+// every remaining game in regulation.
+func isSurelyAhead(rival ClinchRecord, team ClinchRecord) bool {
+	if rival.Points != team.MaxPoints {
+		return rival.Points > team.MaxPoints
+	}
+	return rival.RegulationWins > team.MaxRegulationWins
+}
+
+// missesPlayoffs is whether team misses the playoffs when the given rivals
+// finish ahead of it: the top of each division takes the division seeds, and
+// only the teams ahead of it outside of those can take its wild card.
+func missesPlayoffs(team ClinchRecord, rivalsAhead []ClinchRecord) bool {
+	aheadByDivision := make(map[string]int)
+	for _, rival := range rivalsAhead {
+		if rival.Conference == team.Conference {
+			aheadByDivision[rival.Division] += 1
+		}
+	}
+	if aheadByDivision[team.Division] < seedsPerDivision {
+		return false
+	}
+	wildCardsAhead := 0
+	for _, ahead := range aheadByDivision {
+		if ahead > seedsPerDivision {
+			wildCardsAhead += ahead - seedsPerDivision
+		}
+	}
+	return wildCardsAhead >= wildCardsPerConference
+}
+
+func rivalsWhere(team ClinchRecord, records []ClinchRecord, include func(rival ClinchRecord) bool) []ClinchRecord {
+	rivals := []ClinchRecord{}
+	for _, rival := range records {
+		if rival.Team != team.Team && include(rival) {
+			rivals = append(rivals, rival)
+		}
+	}
+	return rivals
+}
+
+func clinchedPlayoffs(team ClinchRecord, records []ClinchRecord) bool {
+	return !missesPlayoffs(team, rivalsWhere(team, records, func(rival ClinchRecord) bool {
+		return canFinishAhead(rival, team)
+	}))
+}
+
+func eliminatedFromPlayoffs(team ClinchRecord, records []ClinchRecord) bool {
+	return missesPlayoffs(team, rivalsWhere(team, records, func(rival ClinchRecord) bool {
+		return isSurelyAhead(rival, team)
+	}))
+}
+
+// clinchedFirst is whether no rival in the group can finish ahead of team.
+func clinchedFirst(team ClinchRecord, records []ClinchRecord, inGroup func(rival ClinchRecord) bool) bool {
+	return len(rivalsWhere(team, records, func(rival ClinchRecord) bool {
+		return inGroup(rival) && canFinishAhead(rival, team)
+	})) == 0
+}
+
+// eliminatedFromFirst is whether some rival in the group surely finishes ahead of team.
+func eliminatedFromFirst(team ClinchRecord, records []ClinchRecord, inGroup func(rival ClinchRecord) bool) bool {
+	return len(rivalsWhere(team, records, func(rival ClinchRecord) bool {
+		return inGroup(rival) && isSurelyAhead(rival, team)
+	})) > 0
+}
+
+// magicNumber is the fewest points team has to earn to clinch with no help,
+// earning them in regulation wins and any odd point in an overtime loss.
+func magicNumber(team ClinchRecord, records []ClinchRecord, clinched func(team ClinchRecord, records []ClinchRecord) bool) int {
+	for points := 0; points <= team.MaxPoints-team.Points; points++ {
+		withPoints := team
+		withPoints.Points += points
+		withPoints.RegulationWins += points / 2
+		if clinched(withPoints, records) {
+			return points
+		}
+	}
+	return -1
+}
+
+// tragicNumber is the fewest points team can fail to earn before it is
+// eliminated, counting only points it can still earn. Every game it drops a
+// point in is also a regulation win it can no longer get.
+func tragicNumber(team ClinchRecord, records []ClinchRecord) int {
+	for points := 0; points <= team.MaxPoints-team.Points; points++ {
+		withoutPoints := team
+		withoutPoints.MaxPoints -= points
+		withoutPoints.MaxRegulationWins -= (points + 1) / 2
+		if eliminatedFromPlayoffs(withoutPoints, records) {
+			return points
+		}
+	}
+	return -1
+}
+
+// ClinchRecords builds every team's clinch record from the Final games of the
+// season and the games left to play.
+func ClinchRecords(teams map[string]NHLTeamJSON, season []NHLGameCSVRow) []ClinchRecord {
+	seasonStats := CalculateSeasonStats(&teams, &season)
+	remaining := make(map[string]int)
+	for _, game := range season {
+		if game.Status != "Final" {
+			remaining[game.HomeTeam] += 1
+			remaining[game.AwayTeam] += 1
+		}
+	}
+
+	records := []ClinchRecord{}
+	for abbr, team := range teams {
+		stats := seasonStats[abbr]
+		records = append(records, ClinchRecord{
+			Team:              abbr,
+			Division:          team.Division.Name,
+			Conference:        team.Conference.Name,
+			Points:            stats.Points,
+			RegulationWins:    stats.RegulationWins,
+			GamesRemaining:    remaining[abbr],
+			MaxPoints:         stats.Points + 2*remaining[abbr],
+			MaxRegulationWins: stats.RegulationWins + remaining[abbr],
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Points != records[j].Points {
+			return records[i].Points > records[j].Points
+		}
+		return records[i].Team < records[j].Team
+	})
+	return records
+}
+
+// CalculateClinchStatus computes every team's clinch and elimination status
+// from the standings and the games left, without simulating. A team is only
+// marked once no combination of results can change it, but may be marked
+// late; see ClinchStatus.
+func CalculateClinchStatus(teams map[string]NHLTeamJSON, season []NHLGameCSVRow) map[string]ClinchStatus {
+	return ClinchStatusFromRecords(ClinchRecords(teams, season))
+}
+
+func ClinchStatusFromRecords(records []ClinchRecord) map[string]ClinchStatus {
+	clinchedDivision := func(team ClinchRecord, records []ClinchRecord) bool {
+		return clinchedFirst(team, records, func(rival ClinchRecord) bool { return rival.Division == team.Division })
+	}
+	clinchedPresidents := func(team ClinchRecord, records []ClinchRecord) bool {
+		return clinchedFirst(team, records, func(rival ClinchRecord) bool { return true })
+	}
+
+	statuses := make(map[string]ClinchStatus)
+	for _, team := range records {
+		inConference := func(rival ClinchRecord) bool { return rival.Conference == team.Conference }
+		inDivision := func(rival ClinchRecord) bool { return rival.Division == team.Division }
+		anyTeam := func(rival ClinchRecord) bool { return true }
+
+		statuses[team.Team] = ClinchStatus{
+			ClinchRecord:          team,
+			ClinchedPlayoffs:      clinchedPlayoffs(team, records),
+			ClinchedDivision:      clinchedFirst(team, records, inDivision),
+			ClinchedConference:    clinchedFirst(team, records, inConference),
+			ClinchedPresidents:    clinchedFirst(team, records, anyTeam),
+			Eliminated:            eliminatedFromPlayoffs(team, records),
+			EliminatedDivision:    eliminatedFromFirst(team, records, inDivision),
+			EliminatedPresidents:  eliminatedFromFirst(team, records, anyTeam),
+			MagicNumber:           magicNumber(team, records, clinchedPlayoffs),
+			TragicNumber:          tragicNumber(team, records),
+			DivisionMagicNumber:   magicNumber(team, records, clinchedDivision),
+			PresidentsMagicNumber: magicNumber(team, records, clinchedPresidents),
+		}
+	}
+	return statuses
+}
+
+func ClinchMarks(statuses map[string]ClinchStatus) map[string]string {
+	marks := make(map[string]string)
+	for team, status := range statuses {
+		marks[team] = status.Mark()
+	}
+	return marks
+}
+
+func RunClinch() error {
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return err
+	}
+
+	records := ClinchRecords(teams, season)
+	statuses := ClinchStatusFromRecords(records)
+
+	conferences := []string{}
+	byConference := make(map[string][]ClinchRecord)
+	for _, record := range records {
+		if _, ok := byConference[record.Conference]; !ok {
+			conferences = append(conferences, record.Conference)
+		}
+		byConference[record.Conference] = append(byConference[record.Conference], record)
+	}
+	sort.Strings(conferences)
+
+	formatNumber := func(number int, done bool) string {
+		if done {
+			return "-"
+		}
+		if number < 0 {
+			return "n/a"
+		}
+		return fmt.Sprint(number)
+	}
+
+	for _, conference := range conferences {
+		fmt.Printf("%s conference:\n", conference)
+		fmt.Printf("  %-6s %4s %4s %4s %6s %6s %8s %10s\n", "team", "pts", "gr", "max", "magic", "tragic", "div magic", "pres magic")
+		for _, record := range byConference[conference] {
+			status := statuses[record.Team]
+			team := record.Team
+			if mark := status.Mark(); mark != "" {
+				team = fmt.Sprintf("%s-%s", mark, team)
+			}
+			fmt.Printf("  %-6s %4d %4d %4d %6s %6s %8s %10s\n", team, record.Points, record.GamesRemaining, record.MaxPoints,
+				formatNumber(status.MagicNumber, status.ClinchedPlayoffs || status.Eliminated),
+				formatNumber(status.TragicNumber, status.ClinchedPlayoffs || status.Eliminated),
+				formatNumber(status.DivisionMagicNumber, status.ClinchedDivision || status.EliminatedDivision),
+				formatNumber(status.PresidentsMagicNumber, status.ClinchedPresidents || status.EliminatedPresidents))
+		}
+	}
+	fmt.Print("x - clinched playoffs, y - clinched division, z - clinched conference, p - clinched Presidents' Trophy, e - eliminated\n")
+	fmt.Print("marks are guaranteed but can come a game or two late, since rivals are bounded without their games against each other\n")
+	return nil
+}
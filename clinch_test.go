@@ -0,0 +1,167 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// clinchRecord is a record in the test conference with the given points,
+// regulation wins and games left.
+func clinchRecord(team string, points int, regulationWins int, gamesRemaining int) ClinchRecord {
+	division := "Atlantic"
+	if team[0] == 'M' {
+		division = "Metropolitan"
+	}
+	return ClinchRecord{
+		Team:              team,
+		Division:          division,
+		Conference:        "Eastern",
+		Points:            points,
+		RegulationWins:    regulationWins,
+		GamesRemaining:    gamesRemaining,
+		MaxPoints:         points + 2*gamesRemaining,
+		MaxRegulationWins: regulationWins + gamesRemaining,
+	}
+}
+
+// finishedConference is a finished season of the test conference with the
+// given wild card race records swapped in.
+func finishedConference(race ...ClinchRecord) []ClinchRecord {
+	records := []ClinchRecord{
+		clinchRecord("A1", 110, 40, 0),
+		clinchRecord("A2", 100, 35, 0),
+		clinchRecord("A3", 90, 30, 0),
+		clinchRecord("A4", 80, 25, 0),
+		clinchRecord("A5", 60, 15, 0),
+		clinchRecord("M1", 105, 40, 0),
+		clinchRecord("M2", 95, 35, 0),
+		clinchRecord("M3", 85, 30, 0),
+		clinchRecord("M4", 75, 25, 0),
+		clinchRecord("M5", 55, 15, 0),
+	}
+	for _, record := range race {
+		for i := range records {
+			if records[i].Team == record.Team {
+				records[i] = record
+			}
+		}
+	}
+	return records
+}
+
+func TestClinchMarks(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []ClinchRecord
+		want    map[string]string
+	}{
+		{
+			name:    "finished season",
+			records: finishedConference(),
+			want: map[string]string{
+				"A1": "p", "A2": "x", "A3": "x", "A4": "x", "A5": "e",
+				"M1": "y", "M2": "x", "M3": "x", "M4": "x", "M5": "e",
+			},
+		},
+		{
+			name:    "tied on points, decided by regulation wins",
+			records: finishedConference(clinchRecord("A5", 75, 20, 0), clinchRecord("M4", 75, 25, 0)),
+			want: map[string]string{
+				"A1": "p", "A2": "x", "A3": "x", "A4": "x", "A5": "e",
+				"M1": "y", "M2": "x", "M3": "x", "M4": "x", "M5": "e",
+			},
+		},
+		{
+			name:    "tied on points and regulation wins, left to later tiebreaks",
+			records: finishedConference(clinchRecord("A5", 75, 25, 0), clinchRecord("M4", 75, 25, 0)),
+			want: map[string]string{
+				"A1": "p", "A2": "x", "A3": "x", "A4": "x", "A5": "",
+				"M1": "y", "M2": "x", "M3": "x", "M4": "", "M5": "e",
+			},
+		},
+		{
+			name: "games left",
+			records: finishedConference(
+				clinchRecord("A1", 108, 40, 1),
+				clinchRecord("M1", 105, 40, 2),
+				clinchRecord("A5", 74, 20, 2),
+				clinchRecord("M4", 75, 25, 1),
+			),
+			// M1 can still pass A1 for the conference and the Presidents' Trophy
+			want: map[string]string{
+				"A1": "y", "A2": "x", "A3": "x", "A4": "x", "A5": "",
+				"M1": "y", "M2": "x", "M3": "x", "M4": "", "M5": "e",
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := ClinchMarks(ClinchStatusFromRecords(test.records)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: marks %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMagicAndTragicNumbers(t *testing.T) {
+	// A4 and A5 race M4 for the last two wild cards; M4 is surely in
+	records := finishedConference(
+		clinchRecord("A4", 70, 12, 2),
+		clinchRecord("A5", 68, 10, 2),
+		clinchRecord("M4", 90, 30, 0),
+	)
+	statuses := ClinchStatusFromRecords(records)
+
+	// two points in regulation put A4 past everything A5 can reach, since the
+	// win also breaks a tie on regulation wins
+	if got := statuses["A4"].MagicNumber; got != 2 {
+		t.Errorf("A4 magic number %d, want 2", got)
+	}
+	// dropping two points costs A5 the game it needed to win in regulation to
+	// beat A4 on a tie
+	if got := statuses["A5"].TragicNumber; got != 2 {
+		t.Errorf("A5 tragic number %d, want 2", got)
+	}
+	if got := statuses["M5"].TragicNumber; got != 0 {
+		t.Errorf("eliminated M5 tragic number %d, want 0", got)
+	}
+	if got := statuses["A5"].MagicNumber; got != -1 {
+		t.Errorf("A5 magic number %d, want -1 since it can't clinch on its own", got)
+	}
+}
+
+func TestClinchRivalsPlayingEachOther(t *testing.T) {
+	// A5 and M4 play each other in their last game, so only one of them can
+	// pass A4. The bounds treat them separately, so A4's clinch comes late.
+	records := finishedConference(
+		clinchRecord("A4", 69, 25, 0),
+		clinchRecord("A5", 68, 0, 1),
+		clinchRecord("M4", 68, 0, 1),
+	)
+	status := ClinchStatusFromRecords(records)["A4"]
+	if status.ClinchedPlayoffs || status.Eliminated {
+		t.Errorf("A4 clinched %t, eliminated %t; want neither until the game is played", status.ClinchedPlayoffs, status.Eliminated)
+	}
+}
+
+func TestClinchRecords(t *testing.T) {
+	teams := testConferenceTeams()
+	season := []NHLGameCSVRow{
+		{Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 1},
+		{Status: "Final", HomeTeam: "A2", AwayTeam: "A1", HomeScore: 3, AwayScore: 2, IsOT: 1},
+		{Status: "Preview", HomeTeam: "A1", AwayTeam: "M1"},
+		{Status: "Live", HomeTeam: "M1", AwayTeam: "A2"},
+	}
+	byTeam := make(map[string]ClinchRecord)
+	for _, record := range ClinchRecords(teams, season) {
+		byTeam[record.Team] = record
+	}
+	want := map[string]ClinchRecord{
+		"A1": {Team: "A1", Division: "Atlantic", Conference: "Eastern", Points: 3, RegulationWins: 1, GamesRemaining: 1, MaxPoints: 5, MaxRegulationWins: 2},
+		"A2": {Team: "A2", Division: "Atlantic", Conference: "Eastern", Points: 2, RegulationWins: 0, GamesRemaining: 1, MaxPoints: 4, MaxRegulationWins: 1},
+		"M1": {Team: "M1", Division: "Metropolitan", Conference: "Eastern", Points: 0, RegulationWins: 0, GamesRemaining: 2, MaxPoints: 4, MaxRegulationWins: 2},
+	}
+	for team, record := range want {
+		if byTeam[team] != record {
+			t.Errorf("%s: %+v, want %+v", team, byTeam[team], record)
+		}
+	}
+}
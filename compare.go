@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PairedDifference is the difference in a team's playoff odds between a
+// baseline and a variant simulated with the same random numbers, with the 95%
+// interval of the difference from the paired runs.
+type PairedDifference struct {
+	Team      string
+	Baseline  float64
+	Variant   float64
+	Diff      float64
+	HalfWidth float64
+}
+
+// CompareOptions describes the variant to compare against the baseline: a
+// scenario and/or a different K factor or home ice advantage.
+type CompareOptions struct {
+	ScenarioPath string
+	K            float64
+	HomeIce      float64
+	Runs         int
+	Seed         int64
+}
+
+// ComparePlayoffOdds simulates both inputs runs times with the same run
+// indexes, so with shared random streams every game starts from the same
+// random numbers in both, and pairs up each team's playoff results run by run.
+func ComparePlayoffOdds(baseline SimulationInputs, variant SimulationInputs, runs int) []PairedDifference {
+	baselineMade := make(map[string]float64)
+	variantMade := make(map[string]float64)
+	sumSquares := make(map[string]float64)
+	for run := 0; run < runs; run++ {
+		baselineSeason := SimulateSeason(baseline, run)
+		baselinePlayoffs := CalculateStandings(&baseline.Teams, &baselineSeason).PlayoffTeams()
+		variantSeason := SimulateSeason(variant, run)
+		variantPlayoffs := CalculateStandings(&variant.Teams, &variantSeason).PlayoffTeams()
+
+		for team := range baseline.Teams {
+			diff := 0.0
+			if baselinePlayoffs[team] {
+				baselineMade[team] += 1
+				diff -= 1
+			}
+			if variantPlayoffs[team] {
+				variantMade[team] += 1
+				diff += 1
+			}
+			sumSquares[team] += diff * diff
+		}
+	}
+
+	n := float64(runs)
+	differences := []PairedDifference{}
+	for team := range baseline.Teams {
+		diff := (variantMade[team] - baselineMade[team]) / n
+		halfWidth := 0.0
+		if runs > 1 {
+			variance := (sumSquares[team] - n*diff*diff) / (n - 1)
+			halfWidth = confidenceZ * math.Sqrt(math.Max(0, variance)/n)
+		}
+		differences = append(differences, PairedDifference{
+			Team:      team,
+			Baseline:  baselineMade[team] / n,
+			Variant:   variantMade[team] / n,
+			Diff:      diff,
+			HalfWidth: halfWidth,
+		})
+	}
+	sort.Slice(differences, func(i, j int) bool {
+		if math.Abs(differences[i].Diff) != math.Abs(differences[j].Diff) {
+			return math.Abs(differences[i].Diff) > math.Abs(differences[j].Diff)
+		}
+		return differences[i].Team < differences[j].Team
+	})
+	return differences
+}
+
+func RunCompare(options CompareOptions) error {
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().Unix()
+	}
+	fmt.Printf("using seed %d\n", seed)
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+	streams := &RandomStreams{Seed: uint64(seed)}
+
+	baseline := inputs
+	baseline.Streams = streams
+
+	variant := inputs
+	variant.Streams = streams
+	model := DefaultEloModel
+	model.K = options.K
+	model.HomeIce = options.HomeIce
+	variant.Model = &model
+	description := fmt.Sprintf("K %g, home ice %g", options.K, options.HomeIce)
+	if options.ScenarioPath != "" {
+		variant.Scenario, err = LoadScenario(options.ScenarioPath, inputs.Season)
+		if err != nil {
+			return err
+		}
+		description = fmt.Sprintf("%s, scenario %s", description, variant.Scenario.Name)
+	}
+	if variant.Scenario == nil && *variant.Model == DefaultEloModel {
+		return fmt.Errorf("the variant is the same as the baseline; give a scenario, --k or --home-ice")
+	}
+
+	fmt.Printf("baseline: K %g, home ice %g\n", DefaultEloModel.K, DefaultEloModel.HomeIce)
+	fmt.Printf("variant: %s\n", description)
+	differences := ComparePlayoffOdds(baseline, variant, options.Runs)
+
+	fmt.Printf("playoff odds over %d paired runs:\n", options.Runs)
+	fmt.Printf("  %-4s %9s %9s %9s %9s\n", "team", "baseline", "variant", "diff", "95% +/-")
+	for _, difference := range differences {
+		significant := ""
+		if math.Abs(difference.Diff) > difference.HalfWidth {
+			significant = " *"
+		}
+		fmt.Printf("  %-4s %8.2f%% %8.2f%% %+8.2f%% %8.2f%%%s\n", difference.Team, 100*difference.Baseline, 100*difference.Variant,
+			100*difference.Diff, 100*difference.HalfWidth, significant)
+	}
+	fmt.Print("* difference is outside its 95% interval of zero\n")
+	return nil
+}
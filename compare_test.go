@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func compareInputs() SimulationInputs {
+	teams := testConferenceTeams()
+	elos := make(map[string]float64)
+	for team := range teams {
+		elos[team] = 1500
+	}
+	// games draw their random numbers by id, so each needs its own
+	season := lastNightSeason()
+	for i := range season {
+		season[i].GamePK = int64(i + 1)
+	}
+	return SimulationInputs{Elos: elos, Season: season, Teams: teams, Streams: &RandomStreams{Seed: 7}}
+}
+
+func TestComparePlayoffOddsSameInputs(t *testing.T) {
+	// with shared streams the runs pair up exactly, so there is no difference
+	// and no uncertainty about it
+	inputs := compareInputs()
+	for _, difference := range ComparePlayoffOdds(inputs, inputs, 500) {
+		if difference.Diff != 0 || difference.HalfWidth != 0 || difference.Baseline != difference.Variant {
+			t.Errorf("%s: %+v, want no difference", difference.Team, difference)
+		}
+	}
+}
+
+func TestComparePlayoffOddsPairedVariance(t *testing.T) {
+	baseline := compareInputs()
+	variant := compareInputs()
+	// M4 winning at A5 in regulation knocks A5 out of every run
+	variant.Scenario = &Scenario{Results: []ScenarioResult{{GamePK: int64(len(variant.Season) - 1), Winner: "M4"}}}
+	if err := variant.Scenario.validate(variant.Season); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 1000
+	byTeam := make(map[string]PairedDifference)
+	for _, difference := range ComparePlayoffOdds(baseline, variant, runs) {
+		byTeam[difference.Team] = difference
+	}
+
+	a5 := byTeam["A5"]
+	if a5.Variant != 0 || a5.Baseline <= 0 || math.Abs(a5.Diff+a5.Baseline) > testTolerance {
+		t.Fatalf("A5: %+v, want the baseline chance lost", a5)
+	}
+	// each run's difference is -1 when A5 made it in the baseline and 0
+	// otherwise, so the sample variance is p(1-p)n/(n-1)
+	n := float64(runs)
+	p := a5.Baseline
+	want := confidenceZ * math.Sqrt(p*(1-p)/(n-1))
+	if math.Abs(a5.HalfWidth-want) > testTolerance {
+		t.Errorf("A5 half width %f, want %f", a5.HalfWidth, want)
+	}
+
+	// division winners are in either way
+	if a1 := byTeam["A1"]; a1.Diff != 0 || a1.HalfWidth != 0 {
+		t.Errorf("A1: %+v, want no difference", a1)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+)
+
+const cutLinesFile = "data/cutlines.csv"
+
+// the cut line stats collected for every conference, in display order.
+// Points percentages are kept in thousandths so they fit in a Histogram.
+const (
+	LastInPointsStat      = "last_in_points"
+	LastInPointsPctStat   = "last_in_points_pct"
+	FirstOutPointsStat    = "first_out_points"
+	FirstOutPointsPctStat = "first_out_points_pct"
+)
+
+var cutLineStats = []string{
+	LastInPointsStat,
+	LastInPointsPctStat,
+	FirstOutPointsStat,
+	FirstOutPointsPctStat,
+}
+
+var cutLineLabels = map[string]string{
+	LastInPointsStat:      "Last wild card points",
+	LastInPointsPctStat:   "Last wild card points %",
+	FirstOutPointsStat:    "First team out points",
+	FirstOutPointsPctStat: "First team out points %",
+}
+
+func isPointsPctStat(stat string) bool {
+	return stat == LastInPointsPctStat || stat == FirstOutPointsPctStat
+}
+
+// FormatCutLineValue formats a cut line stat's value, showing points
+// percentages the way standings do, e.g. .573.
+func FormatCutLineValue(stat string, value int) string {
+	if isPointsPctStat(stat) {
+		return strings.TrimPrefix(fmt.Sprintf("%.3f", float64(value)/1000), "0")
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+// CutLinePercentiles formats the 5th, 25th, 50th, 75th and 95th percentiles
+// of a cut line stat.
+func CutLinePercentiles(stat string, histogram Histogram) []string {
+	p := histogram.Percentiles()
+	formatted := []string{}
+	for _, value := range []int{p.P5, p.P25, p.Median, p.P75, p.P95} {
+		formatted = append(formatted, FormatCutLineValue(stat, value))
+	}
+	return formatted
+}
+
+// PointsPercentage is the share of available points a team earned.
+func (stats NHLSeasonStats) PointsPercentage() float64 {
+	gamesPlayed := stats.Wins + stats.Losses
+	if gamesPlayed == 0 {
+		return 0
+	}
+	return float64(stats.Points) / float64(2*gamesPlayed)
+}
+
+// CutLines holds a conference's cut line histograms, keyed by stat.
+type CutLines map[string]Histogram
+
+// ConferenceCutLines holds each conference's cut lines, keyed by conference.
+type ConferenceCutLines map[string]CutLines
+
+func NewConferenceCutLines(teams map[string]NHLTeamJSON) ConferenceCutLines {
+	cutLines := make(ConferenceCutLines)
+	for _, team := range teams {
+		if _, ok := cutLines[team.Conference.Name]; ok {
+			continue
+		}
+		cutLines[team.Conference.Name] = make(CutLines)
+		for _, stat := range cutLineStats {
+			cutLines[team.Conference.Name][stat] = make(Histogram)
+		}
+	}
+	return cutLines
+}
+
+// TallyCutLines adds the last wild card team's and the first team out's
+// records in each conference of a simulated season to the cut lines.
+func TallyCutLines(cutLines ConferenceCutLines, standings Standings, teams map[string]NHLTeamJSON, weight float64) {
+	lastWildCards := make(map[string]bool)
+	for _, wildCards := range standings.WildCards {
+		if len(wildCards) > 0 {
+			lastWildCards[wildCards[len(wildCards)-1]] = true
+		}
+	}
+	playoffTeams := standings.PlayoffTeams()
+
+	lastIn := make(map[string]*NHLSeasonStats)
+	firstOut := make(map[string]*NHLSeasonStats)
+	for _, stats := range standings.League {
+		conference := teams[stats.Team].Conference.Name
+		if lastWildCards[stats.Team] {
+			lastIn[conference] = stats
+		} else if _, ok := firstOut[conference]; !ok && !playoffTeams[stats.Team] {
+			firstOut[conference] = stats
+		}
+	}
+
+	for conference, conferenceCutLines := range cutLines {
+		if stats, ok := lastIn[conference]; ok {
+			conferenceCutLines[LastInPointsStat][stats.Points] += weight
+			conferenceCutLines[LastInPointsPctStat][int(math.Round(1000*stats.PointsPercentage()))] += weight
+		}
+		if stats, ok := firstOut[conference]; ok {
+			conferenceCutLines[FirstOutPointsStat][stats.Points] += weight
+			conferenceCutLines[FirstOutPointsPctStat][int(math.Round(1000*stats.PointsPercentage()))] += weight
+		}
+	}
+}
+
+func PrintCutLines(cutLines ConferenceCutLines) {
+	conferences := []string{}
+	for conference := range cutLines {
+		conferences = append(conferences, conference)
+	}
+	sort.Strings(conferences)
+
+	fmt.Print("cut lines (p5/p25/median/p75/p95):\n")
+	for _, conference := range conferences {
+		fmt.Printf("%s:\n", conference)
+		for _, stat := range cutLineStats {
+			fmt.Printf("  %s: %s\n", cutLineLabels[stat], strings.Join(CutLinePercentiles(stat, cutLines[conference][stat]), "/"))
+		}
+		fmt.Printf("  the 2nd wild card needs %d points in 50%% of runs\n", cutLines[conference][LastInPointsStat].Percentiles().Median)
+	}
+}
+
+// CutLineRow is the probability of one value of one cut line stat for a
+// conference.
+type CutLineRow struct {
+	Conference  string  `csv:"conference"`
+	Stat        string  `csv:"stat"`
+	Value       int     `csv:"value"`
+	Probability float64 `csv:"probability"`
+}
+
+// WriteCutLines replaces the cut lines file with the latest run's cut lines,
+// normalized to probabilities.
+func WriteCutLines(cutLines ConferenceCutLines) error {
+	rows := []CutLineRow{}
+	for conference, conferenceCutLines := range cutLines {
+		for _, stat := range cutLineStats {
+			histogram := conferenceCutLines[stat]
+			total := histogram.Total()
+			for _, value := range histogram.Values() {
+				rows = append(rows, CutLineRow{Conference: conference, Stat: stat, Value: value, Probability: histogram[value] / total})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Conference < rows[j].Conference
+	})
+
+	cutLinesCSV, err := os.OpenFile(cutLinesFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer cutLinesCSV.Close()
+
+	return gocsv.MarshalFile(&rows, cutLinesCSV)
+}
+
+func LoadCutLines() (ConferenceCutLines, error) {
+	cutLinesCSV, err := os.Open(cutLinesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer cutLinesCSV.Close()
+
+	rows := []*CutLineRow{}
+	if err := gocsv.UnmarshalFile(cutLinesCSV, &rows); err != nil {
+		return nil, err
+	}
+
+	cutLines := make(ConferenceCutLines)
+	for _, row := range rows {
+		if _, ok := cutLines[row.Conference]; !ok {
+			cutLines[row.Conference] = make(CutLines)
+		}
+		if _, ok := cutLines[row.Conference][row.Stat]; !ok {
+			cutLines[row.Conference][row.Stat] = make(Histogram)
+		}
+		cutLines[row.Conference][row.Stat][row.Value] = row.Probability
+	}
+	return cutLines, nil
+}
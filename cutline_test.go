@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFormatCutLineValue(t *testing.T) {
+	tests := []struct {
+		stat  string
+		value int
+		want  string
+	}{
+		{LastInPointsStat, 95, "95"},
+		{FirstOutPointsStat, 0, "0"},
+		{LastInPointsPctStat, 573, ".573"},
+		{FirstOutPointsPctStat, 50, ".050"},
+		{LastInPointsPctStat, 0, ".000"},
+		{LastInPointsPctStat, 1000, "1.000"},
+	}
+	for _, test := range tests {
+		if got := FormatCutLineValue(test.stat, test.value); got != test.want {
+			t.Errorf("FormatCutLineValue(%s, %d) = %q, want %q", test.stat, test.value, got, test.want)
+		}
+	}
+}
+
+func TestPointsPercentage(t *testing.T) {
+	tests := []struct {
+		stats NHLSeasonStats
+		want  float64
+	}{
+		{NHLSeasonStats{}, 0},
+		{NHLSeasonStats{Wins: 1, Losses: 1, Points: 2}, 0.5},
+		{NHLSeasonStats{Wins: 2, Losses: 2, OTLosses: 1, Points: 5}, 0.625},
+		{NHLSeasonStats{Wins: 4, Points: 8}, 1},
+	}
+	for _, test := range tests {
+		if got := test.stats.PointsPercentage(); math.Abs(got-test.want) > testTolerance {
+			t.Errorf("%+v: points percentage %f, want %f", test.stats, got, test.want)
+		}
+	}
+}
+
+func TestCutLinePercentiles(t *testing.T) {
+	// a percentile is the smallest value with at least that share at or below it
+	histogram := Histogram{540: 5, 550: 20, 560: 50, 570: 20, 580: 5}
+	want := []string{".540", ".550", ".560", ".560", ".570"}
+	if got := CutLinePercentiles(LastInPointsPctStat, histogram); !reflect.DeepEqual(got, want) {
+		t.Errorf("points %% percentiles %v, want %v", got, want)
+	}
+	histogram = Histogram{90: 5, 91: 20, 92: 50, 93: 20, 94: 5}
+	want = []string{"90", "91", "92", "92", "93"}
+	if got := CutLinePercentiles(LastInPointsStat, histogram); !reflect.DeepEqual(got, want) {
+		t.Errorf("points percentiles %v, want %v", got, want)
+	}
+}
+
+func TestTallyCutLines(t *testing.T) {
+	teams := testConferenceTeams()
+	standings := testStandings(map[string]int{}, []string{"A4", "M4"})
+	for _, team := range []string{"A1", "M1", "A2", "M2", "A3", "M3", "A4", "M4", "M5", "A5"} {
+		standings.League = append(standings.League, &NHLSeasonStats{Team: team})
+	}
+	standings.League[7] = &NHLSeasonStats{Team: "M4", Wins: 3, Losses: 1, Points: 6}
+	standings.League[8] = &NHLSeasonStats{Team: "M5", Wins: 2, Losses: 2, OTLosses: 1, Points: 5}
+
+	cutLines := NewConferenceCutLines(teams)
+	TallyCutLines(cutLines, standings, teams, 0.5)
+
+	want := CutLines{
+		LastInPointsStat:      {6: 0.5},
+		LastInPointsPctStat:   {750: 0.5},
+		FirstOutPointsStat:    {5: 0.5},
+		FirstOutPointsPctStat: {625: 0.5},
+	}
+	if !reflect.DeepEqual(cutLines["Eastern"], want) {
+		t.Errorf("cut lines %v, want %v", cutLines["Eastern"], want)
+	}
+}
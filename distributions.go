@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gocarina/gocsv"
+)
+
+const distributionsFile = "data/distributions.csv"
+
+// the distributions collected for every team, in display order
+const (
+	LeagueRankStat       = "league_rank"
+	ConferenceRankStat   = "conference_rank"
+	DivisionRankStat     = "division_rank"
+	PointsStat           = "points"
+	WinsStat             = "wins"
+	RegulationWinsStat   = "regulation_wins"
+	GoalDifferentialStat = "goal_differential"
+)
+
+var distributionStats = []string{
+	LeagueRankStat,
+	ConferenceRankStat,
+	DivisionRankStat,
+	PointsStat,
+	WinsStat,
+	RegulationWinsStat,
+	GoalDifferentialStat,
+}
+
+var distributionLabels = map[string]string{
+	LeagueRankStat:       "League rank",
+	ConferenceRankStat:   "Conference rank",
+	DivisionRankStat:     "Division rank",
+	PointsStat:           "Points",
+	WinsStat:             "Wins",
+	RegulationWinsStat:   "Regulation wins",
+	GoalDifferentialStat: "Goal differential",
+}
+
+// Histogram is a weighted count of how often each value came up.
+type Histogram map[int]float64
+
+func (h Histogram) Total() float64 {
+	total := 0.0
+	for _, weight := range h {
+		total += weight
+	}
+	return total
+}
+
+// Values returns the values that came up, smallest first.
+func (h Histogram) Values() []int {
+	values := []int{}
+	for value := range h {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	return values
+}
+
+// Percentile returns the smallest value with at least p of the weight at or
+// below it.
+func (h Histogram) Percentile(p float64) int {
+	total := h.Total()
+	cumulative := 0.0
+	values := h.Values()
+	for _, value := range values {
+		cumulative += h[value]
+		if cumulative >= p*total {
+			return value
+		}
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+// Percentiles summarizes a histogram by its 5th, 25th, 50th, 75th and 95th
+// percentiles.
+type Percentiles struct {
+	P5     int `json:"p5"`
+	P25    int `json:"p25"`
+	Median int `json:"median"`
+	P75    int `json:"p75"`
+	P95    int `json:"p95"`
+}
+
+func (h Histogram) Percentiles() Percentiles {
+	return Percentiles{
+		P5:     h.Percentile(0.05),
+		P25:    h.Percentile(0.25),
+		Median: h.Percentile(0.5),
+		P75:    h.Percentile(0.75),
+		P95:    h.Percentile(0.95),
+	}
+}
+
+// TeamDistributions holds a team's histogram for each stat across simulated
+// seasons.
+type TeamDistributions map[string]Histogram
+
+func NewTeamDistributions(teams map[string]NHLTeamJSON) map[string]TeamDistributions {
+	distributions := make(map[string]TeamDistributions)
+	for abbr := range teams {
+		distributions[abbr] = make(TeamDistributions)
+		for _, stat := range distributionStats {
+			distributions[abbr][stat] = make(Histogram)
+		}
+	}
+	return distributions
+}
+
+// TallyDistributions adds every team's final ranks and record in a simulated
+// season to its distributions.
+func TallyDistributions(distributions map[string]TeamDistributions, standings Standings, weight float64) {
+	for _, stats := range standings.League {
+		teamDistributions, ok := distributions[stats.Team]
+		if !ok {
+			continue
+		}
+		ranks := standings.Ranks[stats.Team]
+		teamDistributions[LeagueRankStat][ranks.League] += weight
+		teamDistributions[ConferenceRankStat][ranks.Conference] += weight
+		teamDistributions[DivisionRankStat][ranks.Division] += weight
+		teamDistributions[PointsStat][stats.Points] += weight
+		teamDistributions[WinsStat][stats.Wins] += weight
+		teamDistributions[RegulationWinsStat][stats.RegulationWins] += weight
+		teamDistributions[GoalDifferentialStat][stats.GoalsFor-stats.GoalsAgainst] += weight
+	}
+}
+
+func PrintDistributions(distributions map[string]TeamDistributions) {
+	teams := []string{}
+	for team := range distributions {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	fmt.Print("distributions (p5/p25/median/p75/p95):\n")
+	for _, team := range teams {
+		fmt.Printf("%s:", team)
+		for _, stat := range distributionStats {
+			p := distributions[team][stat].Percentiles()
+			fmt.Printf(" %s %d/%d/%d/%d/%d", stat, p.P5, p.P25, p.Median, p.P75, p.P95)
+		}
+		fmt.Print("\n")
+	}
+}
+
+// DistributionRow is the probability of one value of one stat for a team.
+type DistributionRow struct {
+	Team        string  `csv:"team"`
+	Stat        string  `csv:"stat"`
+	Value       int     `csv:"value"`
+	Probability float64 `csv:"probability"`
+}
+
+// WriteDistributions replaces the distributions file with the latest run's
+// distributions, normalized to probabilities.
+func WriteDistributions(distributions map[string]TeamDistributions) error {
+	rows := []DistributionRow{}
+	for team, teamDistributions := range distributions {
+		for _, stat := range distributionStats {
+			histogram := teamDistributions[stat]
+			total := histogram.Total()
+			for _, value := range histogram.Values() {
+				rows = append(rows, DistributionRow{Team: team, Stat: stat, Value: value, Probability: histogram[value] / total})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Team < rows[j].Team
+	})
+
+	distributionsCSV, err := os.OpenFile(distributionsFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer distributionsCSV.Close()
+
+	return gocsv.MarshalFile(&rows, distributionsCSV)
+}
+
+func LoadDistributions() (map[string]TeamDistributions, error) {
+	distributionsCSV, err := os.Open(distributionsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer distributionsCSV.Close()
+
+	rows := []*DistributionRow{}
+	if err := gocsv.UnmarshalFile(distributionsCSV, &rows); err != nil {
+		return nil, err
+	}
+
+	distributions := make(map[string]TeamDistributions)
+	for _, row := range rows {
+		if _, ok := distributions[row.Team]; !ok {
+			distributions[row.Team] = make(TeamDistributions)
+		}
+		if _, ok := distributions[row.Team][row.Stat]; !ok {
+			distributions[row.Team][row.Stat] = make(Histogram)
+		}
+		distributions[row.Team][row.Stat][row.Value] = row.Probability
+	}
+	return distributions, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	tenths := Histogram{}
+	for value := 1; value <= 10; value++ {
+		tenths[value] = 0.1
+	}
+	tests := []struct {
+		name      string
+		histogram Histogram
+		p         float64
+		want      int
+	}{
+		{"empty", Histogram{}, 0.5, 0},
+		{"single value", Histogram{7: 3}, 0.05, 7},
+		{"single value top", Histogram{7: 3}, 0.95, 7},
+		{"zero", Histogram{1: 1, 2: 1}, 0, 1},
+		{"exactly half", Histogram{1: 1, 2: 1}, 0.5, 1},
+		{"just over half", Histogram{1: 1, 2: 1}, 0.51, 2},
+		{"all of it", Histogram{1: 1, 2: 1}, 1, 2},
+		// rounding in the running total can't push p of 1 past the end
+		{"all of it in tenths", tenths, 1, 10},
+		{"weighted", Histogram{80: 0.25, 90: 2.5, 100: 0.25}, 0.05, 80},
+		{"negative values", Histogram{-3: 1, 0: 1, 3: 2}, 0.5, 0},
+	}
+	for _, test := range tests {
+		if got := test.histogram.Percentile(test.p); got != test.want {
+			t.Errorf("%s: percentile %f is %d, want %d", test.name, test.p, got, test.want)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	histogram := Histogram{1: 5, 2: 20, 3: 50, 4: 20, 5: 5}
+	want := Percentiles{P5: 1, P25: 2, Median: 3, P75: 3, P95: 4}
+	if got := histogram.Percentiles(); got != want {
+		t.Errorf("percentiles %+v, want %+v", got, want)
+	}
+	if got := (Histogram{}).Percentiles(); got != (Percentiles{}) {
+		t.Errorf("empty histogram percentiles %+v", got)
+	}
+	if got := histogram.Values(); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("values %v", got)
+	}
+	if got := histogram.Total(); got != 100 {
+		t.Errorf("total %f, want 100", got)
+	}
+}
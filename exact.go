@@ -0,0 +1,112 @@
+package main
+
+import "math"
+
+// exactOutcome is one way a game can end for exact enumeration.
+type exactOutcome struct {
+	isHomeWin  bool
+	isOT       bool
+	isShootout bool
+}
+
+var exactOutcomes = []exactOutcome{
+	{isHomeWin: true},
+	{isHomeWin: true, isOT: true},
+	{isHomeWin: true, isOT: true, isShootout: true},
+	{isHomeWin: false},
+	{isHomeWin: false, isOT: true},
+	{isHomeWin: false, isOT: true, isShootout: true},
+}
+
+// probability is how likely the outcome is under the elo and overtime model,
+// with overtime games split evenly between overtime and the shootout.
+func (o exactOutcome) probability(homeWinPct float64, otChance float64) float64 {
+	prob := 1 - homeWinPct
+	if o.isHomeWin {
+		prob = homeWinPct
+	}
+	if !o.isOT {
+		return prob * (1 - otChance)
+	}
+	return prob * otChance / 2
+}
+
+// ExactCombinations is the number of ways the unplayed games of the season
+// can end, as a float since it overflows quickly.
+func ExactCombinations(season []NHLGameCSVRow) float64 {
+	unplayed := 0
+	for _, game := range season {
+		if game.Status != "Final" {
+			unplayed += 1
+		}
+	}
+	return math.Pow(float64(len(exactOutcomes)), float64(unplayed))
+}
+
+// EnumerateSeasons plays out every combination of results of the unplayed
+// games, updating elos along the way, and hands each season and its
+// standings to observe with the probability of that combination. Each game
+// gets its most likely score for its result, which is what goal differential
+// and goals for tiebreaks see, so callers have to check GoalsDecideStandings
+// before calling the odds exact.
+func EnumerateSeasons(inputs SimulationInputs, observe func(simulatedSeason []NHLGameCSVRow, standings Standings, weight float64)) {
+	gameIndexes := []int{}
+	for i, game := range inputs.Season {
+		if game.Status != "Final" {
+			gameIndexes = append(gameIndexes, i)
+		}
+	}
+
+	simulatedSeason := make([]NHLGameCSVRow, len(inputs.Season))
+	copy(simulatedSeason, inputs.Season)
+	model := inputs.EloModel()
+
+	var enumerate func(depth int, elos map[string]float64, weight float64)
+	enumerate = func(depth int, elos map[string]float64, weight float64) {
+		if depth == len(gameIndexes) {
+			observe(simulatedSeason, CalculateStandings(&inputs.Teams, &simulatedSeason), weight)
+			return
+		}
+
+		game := inputs.Season[gameIndexes[depth]]
+		eloDiff, homeWinPct := model.GameWinProbability(game, elos, &inputs.Teams)
+		otChance := OvertimeProbability(eloDiff)
+		for _, outcome := range exactOutcomes {
+			prob := outcome.probability(homeWinPct, otChance)
+			if prob == 0 {
+				continue
+			}
+			gameElos := make(map[string]float64)
+			for team, elo := range elos {
+				gameElos[team] = elo
+			}
+			homeScore, awayScore := MostLikelyScore(eloDiff, outcome.isHomeWin, outcome.isOT)
+			simulatedSeason[gameIndexes[depth]] = model.RecordGameResult(game, gameElos, eloDiff, homeWinPct, homeScore, awayScore, outcome.isOT, outcome.isShootout)
+			enumerate(depth+1, gameElos, weight*prob)
+		}
+	}
+	enumerate(0, inputs.Elos, 1)
+}
+
+// GoalsDecideStandings is whether any two teams in the standings are only
+// separated by goal differential or goals for, which an enumerated season
+// settles with made-up scores.
+func GoalsDecideStandings(standings Standings, season []NHLGameCSVRow) bool {
+	return len(goalTiebreakTies(standings, &season)) > 0
+}
+
+// ScaleSimulationResults multiplies every tally by factor, to store exact
+// probabilities at the resolution of a full Monte Carlo run.
+func ScaleSimulationResults(simulationResults map[string]*TeamSimulationResults, factor float64) {
+	for _, results := range simulationResults {
+		results.MadePlayoffs *= factor
+		results.D1Seed *= factor
+		results.D2Seed *= factor
+		results.D3Seed *= factor
+		results.WC1 *= factor
+		results.WC2 *= factor
+		results.PresidentsTrophy *= factor
+		results.ConferenceLeader *= factor
+		results.LastOverall *= factor
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExactCombinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     float64
+	}{
+		{"empty season", nil, 1},
+		{"all played", []string{"Final", "Final"}, 1},
+		{"one unplayed", []string{"Final", "Preview"}, 6},
+		{"two unplayed", []string{"Preview", "Live", "Final"}, 36},
+	}
+	for _, test := range tests {
+		season := []NHLGameCSVRow{}
+		for _, status := range test.statuses {
+			season = append(season, NHLGameCSVRow{Status: status})
+		}
+		if got := ExactCombinations(season); got != test.want {
+			t.Errorf("%s: %f combinations, want %f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExactOutcomeProbabilities(t *testing.T) {
+	tests := []struct {
+		homeWinPct float64
+		otChance   float64
+	}{
+		{0.5, 0.2},
+		{0.65, 0.23},
+		{0.3, 0},
+		{1, 0.25},
+	}
+	for _, test := range tests {
+		total, homeWins, overtime := 0.0, 0.0, 0.0
+		for _, outcome := range exactOutcomes {
+			prob := outcome.probability(test.homeWinPct, test.otChance)
+			total += prob
+			if outcome.isHomeWin {
+				homeWins += prob
+			}
+			if outcome.isOT {
+				overtime += prob
+			}
+		}
+		if math.Abs(total-1) > testTolerance {
+			t.Errorf("%+v: outcomes sum to %f", test, total)
+		}
+		if math.Abs(homeWins-test.homeWinPct) > testTolerance {
+			t.Errorf("%+v: home wins %f", test, homeWins)
+		}
+		if math.Abs(overtime-test.otChance) > testTolerance {
+			t.Errorf("%+v: overtime %f", test, overtime)
+		}
+	}
+}
+
+func TestEnumerateSeasons(t *testing.T) {
+	teams := testConferenceTeams()
+	elos := make(map[string]float64)
+	for team := range teams {
+		elos[team] = 1500
+	}
+	elos["A1"] = 1600
+	season := []NHLGameCSVRow{
+		{GamePK: 1, Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 1},
+		{GamePK: 2, Status: "Preview", HomeTeam: "A1", AwayTeam: "M1"},
+		{GamePK: 3, Status: "Preview", HomeTeam: "M2", AwayTeam: "A2"},
+	}
+	inputs := SimulationInputs{Elos: elos, Season: season, Teams: teams}
+
+	seasons, total := 0, 0.0
+	EnumerateSeasons(inputs, func(simulatedSeason []NHLGameCSVRow, standings Standings, weight float64) {
+		seasons++
+		total += weight
+		if simulatedSeason[0] != season[0] {
+			t.Errorf("played game changed to %+v", simulatedSeason[0])
+		}
+		for _, game := range simulatedSeason[1:] {
+			if game.HomeScore == game.AwayScore {
+				t.Errorf("game %d ended tied %d-%d", game.GamePK, game.HomeScore, game.AwayScore)
+			}
+		}
+	})
+	if float64(seasons) != ExactCombinations(season) {
+		t.Errorf("enumerated %d seasons, want %f", seasons, ExactCombinations(season))
+	}
+	if math.Abs(total-1) > testTolerance {
+		t.Errorf("season weights sum to %f", total)
+	}
+	if elos["A1"] != 1600 {
+		t.Errorf("enumeration changed the input elos")
+	}
+}
+
+func TestScaleSimulationResults(t *testing.T) {
+	results := map[string]*TeamSimulationResults{
+		"A1": {MadePlayoffs: 0.5, D1Seed: 0.25, WC2: 0.125, LastOverall: 1},
+	}
+	ScaleSimulationResults(results, 1000)
+	want := TeamSimulationResults{MadePlayoffs: 500, D1Seed: 250, WC2: 125, LastOverall: 1000}
+	if *results["A1"] != want {
+		t.Errorf("scaled results %+v, want %+v", *results["A1"], want)
+	}
+}
+
+func TestGoalsDecideStandings(t *testing.T) {
+	teams := testConferenceTeams()
+	// A1 and A2 get the given games, and every other team but M5 beats M5 a
+	// different number of times, more than either of them
+	seasonWith := func(games ...NHLGameCSVRow) []NHLGameCSVRow {
+		season := games
+		for i, team := range []string{"A3", "A4", "A5", "M1", "M2", "M3", "M4"} {
+			for j := 0; j < i+5; j++ {
+				season = append(season, NHLGameCSVRow{Status: "Final", HomeTeam: team, AwayTeam: "M5", HomeScore: 3, AwayScore: 0})
+			}
+		}
+		return season
+	}
+	win := func(winner string, loser string, isOT int) NHLGameCSVRow {
+		return NHLGameCSVRow{Status: "Final", HomeTeam: winner, AwayTeam: loser, HomeScore: 3 - isOT, AwayScore: 1, IsOT: isOT}
+	}
+	awayWin := func(winner string, loser string) NHLGameCSVRow {
+		return NHLGameCSVRow{Status: "Final", HomeTeam: loser, AwayTeam: winner, HomeScore: 1, AwayScore: 3}
+	}
+
+	tests := []struct {
+		name   string
+		season []NHLGameCSVRow
+		want   bool
+	}{
+		{"tied through every tiebreak but goals", seasonWith(win("A1", "M5", 0), win("A2", "M5", 0)), true},
+		{"tied on points, not regulation wins", seasonWith(win("A1", "M5", 0), win("A2", "M5", 1)), false},
+		// a single game between them is the extra home game, which doesn't count
+		{"one game between them", seasonWith(win("A1", "M5", 0), win("A2", "A1", 0)), true},
+		{"decided by their games", seasonWith(win("A1", "A2", 0), awayWin("A1", "A2"), win("A2", "M5", 0), win("A2", "M5", 0)), false},
+	}
+	for _, test := range tests {
+		standings := CalculateStandings(&teams, &test.season)
+		if got := GoalsDecideStandings(standings, test.season); got != test.want {
+			t.Errorf("%s: goals decide %t, want %t", test.name, got, test.want)
+		}
+	}
+}
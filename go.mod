@@ -4,7 +4,7 @@ go 1.19
 
 require (
 	github.com/gocarina/gocsv v0.0.0-20220927221512-ad3251f9fa25
+	golang.org/x/exp v0.0.0-20221006183845-316c7553db56
 	gonum.org/v1/gonum v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
-
-require golang.org/x/exp v0.0.0-20221006183845-316c7553db56 // indirect
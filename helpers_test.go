@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tolerance for comparing probabilities computed exactly
+const testTolerance = 1e-9
+
+// testConferenceTeams is one conference of two five-team divisions, A1-A5
+// and M1-M5.
+func testConferenceTeams() map[string]NHLTeamJSON {
+	teams := make(map[string]NHLTeamJSON)
+	for _, division := range []string{"Atlantic", "Metropolitan"} {
+		for _, abbr := range []string{"1", "2", "3", "4", "5"} {
+			var team NHLTeamJSON
+			team.Abbreviation = division[:1] + abbr
+			team.Division.Name = division
+			team.Conference.Name = "Eastern"
+			teams[team.Abbreviation] = team
+		}
+	}
+	return teams
+}
+
+// testStandings seeds the conference with the given league ranks.
+func testStandings(leagueRanks map[string]int, wildCards []string) Standings {
+	ranks := make(map[string]TeamRanks)
+	for team, rank := range leagueRanks {
+		ranks[team] = TeamRanks{League: rank}
+	}
+	return Standings{
+		DivisionSeeds: map[string][]string{
+			"Atlantic":     {"A1", "A2", "A3"},
+			"Metropolitan": {"M1", "M2", "M3"},
+		},
+		WildCards: map[string][]string{"Eastern": wildCards},
+		Ranks:     ranks,
+	}
+}
+
+// inTempDataDir runs the rest of the test from an empty directory with a data
+// subdirectory, so code reading and writing data files touches nothing real.
+func inTempDataDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
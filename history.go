@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+const historyFile = "data/history.csv"
+
+// historyTimestampFormat is RFC3339 in UTC with every digit of the
+// nanoseconds kept, so each run gets its own timestamp and they sort as
+// strings. Older history has RFC3339 local times to the second.
+const historyTimestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// HistoryTimestamp formats the time a run finished for the history store.
+func HistoryTimestamp(t time.Time) string {
+	return t.UTC().Format(historyTimestampFormat)
+}
+
+// OddsHistoryRow is one team's results from a single simulate run.
+type OddsHistoryRow struct {
+	Timestamp        string `csv:"timestamp"`
+	Seed             int64  `csv:"seed"`
+	Runs             int    `csv:"runs"`
+	LastFinalGame    int64  `csv:"last_final_game"`
+	LastFinalDate    string `csv:"last_final_date"`
+	Team             string `csv:"team"`
+	MadePlayoffs     int    `csv:"made_playoffs"`
+	D1Seed           int    `csv:"d1"`
+	D2Seed           int    `csv:"d2"`
+	D3Seed           int    `csv:"d3"`
+	WC1              int    `csv:"wc1"`
+	WC2              int    `csv:"wc2"`
+	PresidentsTrophy int    `csv:"presidents"`
+	ConferenceLeader int    `csv:"conference_leader"`
+	LastOverall      int    `csv:"last_overall"`
+}
+
+// Time parses the row's timestamp, in either the current or the older format.
+// An unreadable timestamp is the zero time.
+func (row OddsHistoryRow) Time() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, row.Timestamp)
+	return t
+}
+
+// Day is the local date of the run.
+func (row OddsHistoryRow) Day() string {
+	return row.Time().Local().Format("2006-01-02")
+}
+
+func (row OddsHistoryRow) PlayoffChance() float64 {
+	return 100. * float64(row.MadePlayoffs) / float64(row.Runs)
+}
+
+// LastFinalGame returns the most recently completed game of the season.
+func LastFinalGame(season []NHLGameCSVRow) (NHLGameCSVRow, bool) {
+	var last NHLGameCSVRow
+	found := false
+	for _, game := range season {
+		if game.Status != "Final" {
+			continue
+		}
+		if !found || game.Date > last.Date || (game.Date == last.Date && game.GamePK > last.GamePK) {
+			last = game
+			found = true
+		}
+	}
+	return last, found
+}
+
+func AppendOddsHistory(timestamp time.Time, seed int64, runs int, season []NHLGameCSVRow, simulationResults map[string]*TeamSimulationResults) error {
+	lastGame, _ := LastFinalGame(season)
+
+	rows := []OddsHistoryRow{}
+	for team, results := range simulationResults {
+		rows = append(rows, OddsHistoryRow{
+			Timestamp:        HistoryTimestamp(timestamp),
+			Seed:             seed,
+			Runs:             runs,
+			LastFinalGame:    lastGame.GamePK,
+			LastFinalDate:    lastGame.Date,
+			Team:             team,
+			MadePlayoffs:     int(math.Round(results.MadePlayoffs)),
+			D1Seed:           int(math.Round(results.D1Seed)),
+			D2Seed:           int(math.Round(results.D2Seed)),
+			D3Seed:           int(math.Round(results.D3Seed)),
+			WC1:              int(math.Round(results.WC1)),
+			WC2:              int(math.Round(results.WC2)),
+			PresidentsTrophy: int(math.Round(results.PresidentsTrophy)),
+			ConferenceLeader: int(math.Round(results.ConferenceLeader)),
+			LastOverall:      int(math.Round(results.LastOverall)),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Team < rows[j].Team
+	})
+
+	// history written before the latest columns were added has to be
+	// rewritten with the new header before rows can be appended to it
+	current, err := historyHeaderIsCurrent()
+	if err != nil {
+		return err
+	}
+	if !current {
+		history, err := LoadOddsHistory()
+		if err != nil {
+			return err
+		}
+		return rewriteOddsHistory(append(history, rows...))
+	}
+
+	historyCSV, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer historyCSV.Close()
+
+	info, err := historyCSV.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return gocsv.Marshal(&rows, historyCSV)
+	}
+	return gocsv.MarshalWithoutHeaders(&rows, historyCSV)
+}
+
+// rewriteOddsHistory replaces the history file with rows. They are written to
+// a temporary file next to it first, so a failed write leaves the old history
+// as it was.
+func rewriteOddsHistory(rows []OddsHistoryRow) error {
+	tempCSV, err := os.CreateTemp(filepath.Dir(historyFile), "history-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempCSV.Name())
+
+	if err := gocsv.Marshal(&rows, tempCSV); err != nil {
+		tempCSV.Close()
+		return err
+	}
+	if err := tempCSV.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempCSV.Name(), historyFile)
+}
+
+// historyHeaderIsCurrent reports whether the history file is missing, empty
+// or has the columns OddsHistoryRow currently writes.
+func historyHeaderIsCurrent() (bool, error) {
+	historyCSV, err := os.Open(historyFile)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer historyCSV.Close()
+
+	existingHeader, err := bufio.NewReader(historyCSV).ReadString('\n')
+	if err == io.EOF && existingHeader == "" {
+		return true, nil
+	} else if err != nil && err != io.EOF {
+		return false, err
+	}
+	header, err := gocsv.MarshalString(&[]OddsHistoryRow{})
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(existingHeader) == strings.TrimSpace(header), nil
+}
+
+func LoadOddsHistory() ([]OddsHistoryRow, error) {
+	historyCSV, err := os.OpenFile(historyFile, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer historyCSV.Close()
+
+	history := []OddsHistoryRow{}
+	if err := gocsv.UnmarshalFile(historyCSV, &history); err != nil {
+		return nil, err
+	}
+
+	// older timestamps carry their own UTC offset, so compare the times
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time().Before(history[j].Time())
+	})
+	return history, nil
+}
+
+// LatestSimulationResults returns the results of the most recent simulate run
+// in the history store, along with one of its rows for the run metadata.
+func LatestSimulationResults() (map[string]*TeamSimulationResults, OddsHistoryRow, error) {
+	history, err := LoadOddsHistory()
+	if err != nil {
+		return nil, OddsHistoryRow{}, err
+	}
+	if len(history) == 0 {
+		return nil, OddsHistoryRow{}, fmt.Errorf("no simulation history in %s", historyFile)
+	}
+
+	latest := history[len(history)-1]
+	simulationResults := make(map[string]*TeamSimulationResults)
+	for _, row := range history {
+		if row.Timestamp != latest.Timestamp {
+			continue
+		}
+		// runs from before the presidents', conference leader and last
+		// overall odds were tracked load them as zero
+		simulationResults[row.Team] = &TeamSimulationResults{
+			MadePlayoffs:     float64(row.MadePlayoffs),
+			D1Seed:           float64(row.D1Seed),
+			D2Seed:           float64(row.D2Seed),
+			D3Seed:           float64(row.D3Seed),
+			WC1:              float64(row.WC1),
+			WC2:              float64(row.WC2),
+			PresidentsTrophy: float64(row.PresidentsTrophy),
+			ConferenceLeader: float64(row.ConferenceLeader),
+			LastOverall:      float64(row.LastOverall),
+		}
+	}
+	return simulationResults, latest, nil
+}
+
+// LatestRuns returns the timestamps of the last two runs in history sorted by
+// LoadOddsHistory. previous is empty if there is only one run.
+func LatestRuns(history []OddsHistoryRow) (latest string, previous string) {
+	if len(history) == 0 {
+		return "", ""
+	}
+	latest = history[len(history)-1].Timestamp
+	for _, row := range history {
+		if row.Timestamp != latest {
+			previous = row.Timestamp
+		}
+	}
+	return latest, previous
+}
+
+func ShowOddsHistory(team string, numMovers int) error {
+	history, err := LoadOddsHistory()
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return fmt.Errorf("no simulation history in %s", historyFile)
+	}
+
+	if team != "" {
+		// keep the last run of each day
+		dailyRows := []OddsHistoryRow{}
+		for _, row := range history {
+			if !strings.EqualFold(row.Team, team) {
+				continue
+			}
+			if len(dailyRows) > 0 && dailyRows[len(dailyRows)-1].Day() == row.Day() {
+				dailyRows[len(dailyRows)-1] = row
+			} else {
+				dailyRows = append(dailyRows, row)
+			}
+		}
+		if len(dailyRows) == 0 {
+			return fmt.Errorf("no simulation history for team %s", team)
+		}
+
+		fmt.Printf("%s odds history:\n", dailyRows[0].Team)
+		var previousChance float64
+		for i, row := range dailyRows {
+			runs := float64(row.Runs)
+			change := ""
+			if i > 0 {
+				change = fmt.Sprintf(" (%+.1f)", row.PlayoffChance()-previousChance)
+			}
+			fmt.Printf("  %s through %s: %5.1f%% playoffs%s (%.1f D1, %.1f D2, %.1f D3, %.1f WC1, %.1f WC2; %.1f presidents, %.1f conference, %.1f last)\n",
+				row.Day(), row.LastFinalDate, row.PlayoffChance(), change,
+				100.*float64(row.D1Seed)/runs, 100.*float64(row.D2Seed)/runs, 100.*float64(row.D3Seed)/runs,
+				100.*float64(row.WC1)/runs, 100.*float64(row.WC2)/runs,
+				100.*float64(row.PresidentsTrophy)/runs, 100.*float64(row.ConferenceLeader)/runs, 100.*float64(row.LastOverall)/runs)
+			previousChance = row.PlayoffChance()
+		}
+	}
+
+	latest, previous := LatestRuns(history)
+	if previous == "" {
+		return nil
+	}
+
+	previousChances := make(map[string]float64)
+	type mover struct {
+		team   string
+		chance float64
+		change float64
+	}
+	movers := []mover{}
+	for _, row := range history {
+		if row.Timestamp == previous {
+			previousChances[row.Team] = row.PlayoffChance()
+		}
+	}
+	for _, row := range history {
+		if row.Timestamp != latest {
+			continue
+		}
+		if previousChance, ok := previousChances[row.Team]; ok {
+			movers = append(movers, mover{team: row.Team, chance: row.PlayoffChance(), change: row.PlayoffChance() - previousChance})
+		}
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		return math.Abs(movers[i].change) > math.Abs(movers[j].change)
+	})
+	if len(movers) > numMovers {
+		movers = movers[:numMovers]
+	}
+
+	fmt.Printf("biggest movers since %s:\n", previous)
+	for _, m := range movers {
+		fmt.Printf("  %s: %5.1f%% playoffs (%+.1f)\n", m.team, m.chance, m.change)
+	}
+	return nil
+}
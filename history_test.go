@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryTimestamp(t *testing.T) {
+	// two runs in the same second still get their own timestamps
+	first := time.Date(2023, 3, 1, 22, 30, 5, 100, time.FixedZone("EST", -5*60*60))
+	second := first.Add(time.Millisecond)
+	if HistoryTimestamp(first) == HistoryTimestamp(second) || HistoryTimestamp(first) > HistoryTimestamp(second) {
+		t.Errorf("timestamps %s and %s don't sort apart", HistoryTimestamp(first), HistoryTimestamp(second))
+	}
+	if want := "2023-03-02T03:30:05.000000100Z"; HistoryTimestamp(first) != want {
+		t.Errorf("timestamp %s, want %s", HistoryTimestamp(first), want)
+	}
+	row := OddsHistoryRow{Timestamp: HistoryTimestamp(first)}
+	if !row.Time().Equal(first) {
+		t.Errorf("timestamp parsed as %s, want %s", row.Time(), first)
+	}
+	if want := first.Local().Format("2006-01-02"); row.Day() != want {
+		t.Errorf("day %s, want the local date %s", row.Day(), want)
+	}
+}
+
+func TestLatestRuns(t *testing.T) {
+	inTempDataDir(t)
+	// an older run written in local time with an offset, which sorts after the
+	// newer UTC runs as a string
+	history := "timestamp,seed,runs,last_final_game,last_final_date,team,made_playoffs,d1,d2,d3,wc1,wc2,presidents,conference_leader,last_overall\n" +
+		"2023-03-02T03:30:05.000000000Z,2,100,1,2023-03-01,A1,60,0,0,0,0,0,0,0,0\n" +
+		"2023-03-01T21:00:00-05:00,1,100,1,2023-03-01,A1,50,0,0,0,0,0,0,0,0\n" +
+		"2023-03-02T03:30:05.000000001Z,3,100,1,2023-03-01,A1,70,0,0,0,0,0,0,0,0\n"
+	if err := os.WriteFile(historyFile, []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := LoadOddsHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeds := []int64{}
+	for _, row := range rows {
+		seeds = append(seeds, row.Seed)
+	}
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(seeds, want) {
+		t.Errorf("runs loaded in seed order %v, want %v", seeds, want)
+	}
+
+	latest, previous := LatestRuns(rows)
+	if latest != "2023-03-02T03:30:05.000000001Z" || previous != "2023-03-02T03:30:05.000000000Z" {
+		t.Errorf("latest %s and previous %s runs", latest, previous)
+	}
+	if _, previous := LatestRuns(rows[2:]); previous != "" {
+		t.Errorf("previous run %s with only one run", previous)
+	}
+	if latest, previous := LatestRuns(nil); latest != "" || previous != "" {
+		t.Errorf("latest %s and previous %s runs with no history", latest, previous)
+	}
+}
+
+func TestAppendOddsHistoryMigratesHeader(t *testing.T) {
+	inTempDataDir(t)
+	// history from before the presidents', conference leader and last overall
+	// odds were tracked
+	old := "timestamp,seed,runs,last_final_game,last_final_date,team,made_playoffs,d1,d2,d3,wc1,wc2\n" +
+		"2023-03-01T21:00:00-05:00,1,100,1,2023-03-01,A1,50,10,10,10,10,10\n"
+	if err := os.WriteFile(historyFile, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	season := []NHLGameCSVRow{{GamePK: 2, Date: "2023-03-02", Status: "Final"}}
+	finished := time.Date(2023, 3, 3, 3, 0, 0, 0, time.UTC)
+	results := map[string]*TeamSimulationResults{"A1": {MadePlayoffs: 60, PresidentsTrophy: 5}}
+	if err := AppendOddsHistory(finished, 2, 100, season, results); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := historyHeaderIsCurrent()
+	if err != nil || !current {
+		t.Errorf("header current %t, error %v after the rewrite", current, err)
+	}
+	rows, err := LoadOddsHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OddsHistoryRow{
+		{Timestamp: "2023-03-01T21:00:00-05:00", Seed: 1, Runs: 100, LastFinalGame: 1, LastFinalDate: "2023-03-01", Team: "A1", MadePlayoffs: 50, D1Seed: 10, D2Seed: 10, D3Seed: 10, WC1: 10, WC2: 10},
+		{Timestamp: "2023-03-03T03:00:00.000000000Z", Seed: 2, Runs: 100, LastFinalGame: 2, LastFinalDate: "2023-03-02", Team: "A1", MadePlayoffs: 60, PresidentsTrophy: 5},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("history %+v, want %+v", rows, want)
+	}
+
+	// later runs are appended under the new header
+	if err := AppendOddsHistory(finished.Add(time.Hour), 3, 100, season, results); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(contents)), "\n"); len(lines) != 4 || !strings.HasSuffix(lines[0], ",last_overall") {
+		t.Errorf("history file after appending:\n%s", contents)
+	}
+	entries, err := os.ReadDir("data")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("data directory has %v, want only the history file", entries)
+	}
+}
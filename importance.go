@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gocarina/gocsv"
+)
+
+// game ratings live apart from the season file so simulating never rewrites
+// its own input
+const gameRatingsFile = "data/ratings.csv"
+
+// elo range mapped onto the 0-100 game quality scale
+const (
+	qualityMinElo = 1350.
+	qualityMaxElo = 1650.
+)
+
+// GameImportanceTally buckets both teams' playoff appearances by the result
+// of every unplayed game across simulation runs.
+type GameImportanceTally struct {
+	gameIndexes  []int
+	runs         [][2]float64
+	homePlayoffs [][2]float64
+	awayPlayoffs [][2]float64
+}
+
+func NewGameImportanceTally(season []NHLGameCSVRow) *GameImportanceTally {
+	tally := &GameImportanceTally{}
+	for i, game := range season {
+		if game.Status != "Final" {
+			tally.gameIndexes = append(tally.gameIndexes, i)
+		}
+	}
+	tally.runs = make([][2]float64, len(tally.gameIndexes))
+	tally.homePlayoffs = make([][2]float64, len(tally.gameIndexes))
+	tally.awayPlayoffs = make([][2]float64, len(tally.gameIndexes))
+	return tally
+}
+
+func (t *GameImportanceTally) Observe(simulatedSeason []NHLGameCSVRow, standings Standings) {
+	t.ObserveWeighted(simulatedSeason, standings, 1)
+}
+
+// ObserveWeighted counts a simulated season with the given weight, for
+// seasons that were enumerated rather than sampled.
+func (t *GameImportanceTally) ObserveWeighted(simulatedSeason []NHLGameCSVRow, standings Standings, weight float64) {
+	madePlayoffs := standings.PlayoffTeams()
+
+	for j, i := range t.gameIndexes {
+		game := simulatedSeason[i]
+		result := 0
+		if game.HomeScore > game.AwayScore {
+			result = 1
+		}
+		t.runs[j][result] += weight
+		if madePlayoffs[game.HomeTeam] {
+			t.homePlayoffs[j][result] += weight
+		}
+		if madePlayoffs[game.AwayTeam] {
+			t.awayPlayoffs[j][result] += weight
+		}
+	}
+}
+
+// Importance returns, by season index, how much each unplayed game's result
+// moves the playoff odds of the two teams playing in it, as the sum of both
+// teams' swings in percentage points capped at 100.
+func (t *GameImportanceTally) Importance() map[int]int {
+	importance := make(map[int]int)
+	for j, i := range t.gameIndexes {
+		runs := t.runs[j]
+		if runs[0] == 0 || runs[1] == 0 {
+			importance[i] = 0
+			continue
+		}
+		homeSwing := t.homePlayoffs[j][1]/runs[1] - t.homePlayoffs[j][0]/runs[0]
+		awaySwing := t.awayPlayoffs[j][0]/runs[0] - t.awayPlayoffs[j][1]/runs[1]
+		importance[i] = int(math.Min(100, math.Round(100*(math.Abs(homeSwing)+math.Abs(awaySwing)))))
+	}
+	return importance
+}
+
+// GameQuality rates a matchup from 0 to 100 by the harmonic mean of both
+// teams' elos on a 0-100 scale, so a game is only as good as its weaker team.
+func GameQuality(homeElo float64, awayElo float64) int {
+	scale := func(elo float64) float64 {
+		return math.Max(1, math.Min(100, 100*(elo-qualityMinElo)/(qualityMaxElo-qualityMinElo)))
+	}
+	home, away := scale(homeElo), scale(awayElo)
+	return int(math.Round(2 / (1/home + 1/away)))
+}
+
+// ApplyGameRatings sets the quality, importance and overall rating of every
+// unplayed game in the season.
+func ApplyGameRatings(season []NHLGameCSVRow, elos map[string]float64, importance map[int]int) {
+	for i, gameImportance := range importance {
+		game := &season[i]
+		game.Quality = GameQuality(elos[game.HomeTeam], elos[game.AwayTeam])
+		game.Importance = gameImportance
+		game.Overall = int(math.Round(float64(game.Quality+game.Importance) / 2))
+	}
+}
+
+// GameRatingRow is the quality, importance and overall rating the simulator
+// last gave a game.
+type GameRatingRow struct {
+	GamePK     int64 `csv:"game_pk"`
+	Quality    int   `csv:"quality"`
+	Importance int   `csv:"importance"`
+	Overall    int   `csv:"overall"`
+}
+
+// LoadGameRatings returns the saved game ratings by GamePK, or none if no
+// simulation has saved any yet.
+func LoadGameRatings() (map[int64]GameRatingRow, error) {
+	ratings := make(map[int64]GameRatingRow)
+	ratingsCSV, err := os.Open(gameRatingsFile)
+	if os.IsNotExist(err) {
+		return ratings, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer ratingsCSV.Close()
+
+	rows := []GameRatingRow{}
+	if err := gocsv.UnmarshalFile(ratingsCSV, &rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		ratings[row.GamePK] = row
+	}
+	return ratings, nil
+}
+
+// WriteGameRatings saves the ratings of the season's unplayed games, keeping
+// the ones saved for games played since. The file is replaced through a
+// temporary file so readers never see it half written.
+func WriteGameRatings(season []NHLGameCSVRow) error {
+	ratings, err := LoadGameRatings()
+	if err != nil {
+		return err
+	}
+	for _, game := range season {
+		if game.Status != "Final" {
+			ratings[game.GamePK] = GameRatingRow{GamePK: game.GamePK, Quality: game.Quality, Importance: game.Importance, Overall: game.Overall}
+		}
+	}
+	rows := []GameRatingRow{}
+	for _, row := range ratings {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].GamePK < rows[j].GamePK
+	})
+
+	tempCSV, err := os.CreateTemp(filepath.Dir(gameRatingsFile), "ratings-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempCSV.Name())
+
+	if err := gocsv.Marshal(&rows, tempCSV); err != nil {
+		tempCSV.Close()
+		return err
+	}
+	if err := tempCSV.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempCSV.Name(), gameRatingsFile)
+}
+
+// withGameRatings sets each game's ratings from the saved ones, leaving games
+// without saved ratings as they are.
+func withGameRatings(games []NHLGameCSVRow, ratings map[int64]GameRatingRow) {
+	for i := range games {
+		if rating, ok := ratings[games[i].GamePK]; ok {
+			games[i].Quality = rating.Quality
+			games[i].Importance = rating.Importance
+			games[i].Overall = rating.Overall
+		}
+	}
+}
+
+func ShowGames(date string) error {
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	games := []NHLGameCSVRow{}
+	for _, game := range inputs.Season {
+		if game.Date == date {
+			games = append(games, game)
+		}
+	}
+	if len(games) == 0 {
+		return fmt.Errorf("no games on %s", date)
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].Overall > games[j].Overall
+	})
+
+	fmt.Printf("games on %s:\n", date)
+	for _, game := range games {
+		if game.Status == "Final" {
+			fmt.Printf("  %s %d at %s %d (final)", game.AwayTeam, game.AwayScore, game.HomeTeam, game.HomeScore)
+		} else {
+			_, homeWinPct := GameWinProbability(game, inputs.Elos, &inputs.Teams)
+			fmt.Printf("  %s at %s (%s %.1f%%)", game.AwayTeam, game.HomeTeam, game.HomeTeam, 100*homeWinPct)
+		}
+		fmt.Printf(" quality %d, importance %d, overall %d\n", game.Quality, game.Importance, game.Overall)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWriteGameRatings(t *testing.T) {
+	inTempDataDir(t)
+
+	season := []NHLGameCSVRow{
+		{GamePK: 1, Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 1},
+		{GamePK: 2, Status: "Preview", HomeTeam: "A2", AwayTeam: "A1", Quality: 60, Importance: 40, Overall: 50},
+		{GamePK: 3, Status: "Preview", HomeTeam: "A1", AwayTeam: "A3", Quality: 70, Importance: 10, Overall: 40},
+	}
+	if err := WriteNHLSeason(season); err != nil {
+		t.Fatal(err)
+	}
+	seasonFile := fmt.Sprintf("data/%s.csv", currentSeason)
+	before, err := os.ReadFile(seasonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteGameRatings(season); err != nil {
+		t.Fatal(err)
+	}
+	// game 2 is played, and the next run only rates game 3
+	season[1].Status = "Final"
+	season[2].Quality, season[2].Importance, season[2].Overall = 80, 20, 50
+	if err := WriteGameRatings(season); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.ReadFile(seasonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("saving game ratings changed the season file")
+	}
+
+	ratings, err := LoadGameRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]GameRatingRow{
+		2: {GamePK: 2, Quality: 60, Importance: 40, Overall: 50},
+		3: {GamePK: 3, Quality: 80, Importance: 20, Overall: 50},
+	}
+	if !reflect.DeepEqual(ratings, want) {
+		t.Errorf("ratings %+v, want %+v", ratings, want)
+	}
+}
+
+func TestLoadNHLGamesWithRatings(t *testing.T) {
+	inTempDataDir(t)
+
+	if ratings, err := LoadGameRatings(); err != nil || len(ratings) != 0 {
+		t.Fatalf("ratings without a ratings file: %v, %v", ratings, err)
+	}
+
+	season := []NHLGameCSVRow{
+		{GamePK: 1, Status: "Preview", HomeTeam: "A1", AwayTeam: "A2"},
+		{GamePK: 2, Status: "Preview", HomeTeam: "A2", AwayTeam: "A1", Quality: 5, Importance: 5, Overall: 5},
+	}
+	if err := WriteNHLSeason(season); err != nil {
+		t.Fatal(err)
+	}
+	rated := []NHLGameCSVRow{{GamePK: 1, Status: "Preview", Quality: 60, Importance: 40, Overall: 50}}
+	if err := WriteGameRatings(rated); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _, err := LoadNHLGames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game := loaded[0]; game.Quality != 60 || game.Importance != 40 || game.Overall != 50 {
+		t.Errorf("game 1 ratings %d/%d/%d, want 60/40/50", game.Quality, game.Importance, game.Overall)
+	}
+	if game := loaded[1]; game.Quality != 5 || game.Importance != 5 || game.Overall != 5 {
+		t.Errorf("game 2 without saved ratings has %d/%d/%d, want its own 5/5/5", game.Quality, game.Importance, game.Overall)
+	}
+}
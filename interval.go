@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// z score for the 95% confidence intervals reported with simulation odds
+const confidenceZ = 1.96
+
+// teams whose playoff odds are within this of 0 or 1 are not near the
+// playoff line and don't hold up precision-based stopping
+const playoffLineMargin = 0.01
+
+// WilsonInterval is the 95% Wilson score interval for a probability estimated
+// from successes out of runs.
+func WilsonInterval(successes float64, runs int) (float64, float64) {
+	if runs == 0 {
+		return 0, 1
+	}
+	n := float64(runs)
+	p := successes / n
+	z2 := confidenceZ * confidenceZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	halfWidth := confidenceZ / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return math.Max(0, center-halfWidth), math.Min(1, center+halfWidth)
+}
+
+// WidestPlayoffInterval is the largest half width of the playoff odds
+// intervals of the teams near the playoff line.
+func WidestPlayoffInterval(simulationResults map[string]*TeamSimulationResults, runs int) float64 {
+	widest := 0.0
+	for _, results := range simulationResults {
+		p := results.MadePlayoffs / float64(runs)
+		if p < playoffLineMargin || p > 1-playoffLineMargin {
+			continue
+		}
+		low, high := WilsonInterval(results.MadePlayoffs, runs)
+		widest = math.Max(widest, (high-low)/2)
+	}
+	return widest
+}
+
+// FormatProbability formats an estimated probability as a percentage with its
+// interval, with only as many decimals as the interval supports. Exact
+// probabilities have no interval.
+func FormatProbability(successes float64, runs int, exact bool) string {
+	p := successes / float64(runs)
+	if exact {
+		return fmt.Sprintf("%.3f%%", 100*p)
+	}
+	low, high := WilsonInterval(successes, runs)
+	decimals := 1
+	if halfWidth := 100 * (high - low) / 2; halfWidth > 0 {
+		decimals = int(math.Max(0, math.Min(3, math.Ceil(-math.Log10(halfWidth)))))
+	}
+	return fmt.Sprintf("%.*f%% [%.*f-%.*f]", decimals, 100*p, decimals, 100*low, decimals, 100*high)
+}
+
+// ParsePrecision parses a precision like "0.1%" or "0.001" into a probability.
+func ParsePrecision(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return parseProbability("precision", value)
+}
+
+// parseProbability parses a percentage like "90%" or a fraction like "0.9",
+// naming what it was parsing in errors.
+func parseProbability(name string, value string) (float64, error) {
+	isPercent := strings.HasSuffix(value, "%")
+	probability, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s: %w", name, value, err)
+	}
+	if isPercent {
+		probability /= 100
+	}
+	if probability <= 0 || probability >= 1 {
+		return 0, fmt.Errorf("%s %s must be between 0 and 100%%", name, value)
+	}
+	return probability, nil
+}
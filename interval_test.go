@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWilsonInterval(t *testing.T) {
+	tests := []struct {
+		successes float64
+		runs      int
+		wantLow   float64
+		wantHigh  float64
+	}{
+		{0, 0, 0, 1},
+		{50, 100, 0.40382982859014716, 0.5961701714098528},
+		{0, 10, 0, 0.2775401687666166},
+		{10, 10, 0.7224598312333834, 1},
+		{900, 1000, 0.8798476375941197, 0.9170908435367681},
+	}
+	for _, test := range tests {
+		low, high := WilsonInterval(test.successes, test.runs)
+		if math.Abs(low-test.wantLow) > testTolerance || math.Abs(high-test.wantHigh) > testTolerance {
+			t.Errorf("WilsonInterval(%f, %d) = [%f, %f], want [%f, %f]", test.successes, test.runs, low, high, test.wantLow, test.wantHigh)
+		}
+	}
+}
+
+func TestWidestPlayoffInterval(t *testing.T) {
+	results := map[string]*TeamSimulationResults{
+		"A1": {MadePlayoffs: 1000},
+		"A2": {MadePlayoffs: 500},
+		"A3": {MadePlayoffs: 900},
+		"A4": {MadePlayoffs: 2},
+	}
+	low, high := WilsonInterval(500, 1000)
+	if got := WidestPlayoffInterval(results, 1000); math.Abs(got-(high-low)/2) > testTolerance {
+		t.Errorf("widest interval %f, want %f", got, (high-low)/2)
+	}
+	// teams that are all but in or out don't count
+	delete(results, "A2")
+	delete(results, "A3")
+	if got := WidestPlayoffInterval(results, 1000); got != 0 {
+		t.Errorf("widest interval without teams near the line %f, want 0", got)
+	}
+}
+
+func TestFormatProbability(t *testing.T) {
+	tests := []struct {
+		successes float64
+		runs      int
+		exact     bool
+		want      string
+	}{
+		{50, 100, false, "50% [40-60]"},
+		{900, 1000, false, "90% [88-92]"},
+		{500000, 1000000, false, "50.00% [49.90-50.10]"},
+		{50, 100, true, "50.000%"},
+	}
+	for _, test := range tests {
+		if got := FormatProbability(test.successes, test.runs, test.exact); got != test.want {
+			t.Errorf("FormatProbability(%f, %d, %t) = %q, want %q", test.successes, test.runs, test.exact, got, test.want)
+		}
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0.1%", 0.001, false},
+		{"0.001", 0.001, false},
+		{"90%", 0.9, false},
+		{"0", 0, true},
+		{"100%", 0, true},
+		{"1", 0, true},
+		{"-5%", 0, true},
+		{"abc", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParsePrecision(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParsePrecision(%q) error %v, want error %t", test.value, err, test.wantErr)
+			continue
+		}
+		if math.Abs(got-test.want) > testTolerance {
+			t.Errorf("ParsePrecision(%q) = %f, want %f", test.value, got, test.want)
+		}
+	}
+}
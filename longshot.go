@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Tilt skews the unplayed games toward a target team for importance
+// sampling. Its win probability is pushed up by Strength on the logit scale,
+// and its conference rivals' are pushed down by RivalStrength when they play
+// teams from the other conference.
+type Tilt struct {
+	Team          string
+	Conference    string
+	Strength      float64
+	RivalStrength float64
+}
+
+// HomeWinProbability is the tilted home win probability of a game.
+func (t Tilt) HomeWinProbability(game NHLGameCSVRow, homeWinPct float64, teams map[string]NHLTeamJSON) float64 {
+	shift := 0.0
+	homeRival := teams[game.HomeTeam].Conference.Name == t.Conference
+	awayRival := teams[game.AwayTeam].Conference.Name == t.Conference
+	switch {
+	case game.HomeTeam == t.Team:
+		shift = t.Strength
+	case game.AwayTeam == t.Team:
+		shift = -t.Strength
+	case homeRival && !awayRival:
+		shift = -t.RivalStrength
+	case awayRival && !homeRival:
+		shift = t.RivalStrength
+	}
+	if shift == 0 {
+		return homeWinPct
+	}
+	logit := math.Log(homeWinPct/(1-homeWinPct)) + shift
+	return 1 / (1 + math.Exp(-logit))
+}
+
+// SimulateTiltedSeason simulates the unplayed games once with tilted win
+// probabilities. It returns the season and the elos at the end of it with the
+// likelihood ratio of the run, the weight that makes results from tilted runs
+// unbiased for the real model.
+func SimulateTiltedSeason(inputs SimulationInputs, tilt Tilt) ([]NHLGameCSVRow, map[string]float64, float64) {
+	seasonElos := make(map[string]float64)
+	for team, elo := range inputs.Elos {
+		seasonElos[team] = elo
+	}
+
+	simulator := GameSimulator{Model: inputs.EloModel()}
+	weight := 1.0
+	seasonGames := []NHLGameCSVRow{}
+	for _, game := range inputs.Season {
+		if game.Status == "Final" {
+			seasonGames = append(seasonGames, game)
+			continue
+		}
+
+		eloDiff, homeWinPct := simulator.Model.GameWinProbability(game, seasonElos, &inputs.Teams)
+		tiltedWinPct := tilt.HomeWinProbability(game, homeWinPct, inputs.Teams)
+		isHomeWin := rand.Float64() < tiltedWinPct
+		if isHomeWin {
+			weight *= homeWinPct / tiltedWinPct
+		} else {
+			weight *= (1 - homeWinPct) / (1 - tiltedWinPct)
+		}
+		seasonGames = append(seasonGames, simulator.SimulateGameWithWinner(game, seasonElos, eloDiff, homeWinPct, isHomeWin))
+	}
+	return seasonGames, seasonElos, weight
+}
+
+// RareEventEstimate is an importance sampling estimate of a small
+// probability with its 95% interval. EffectiveRuns is the effective sample
+// size of the weighted runs that hit.
+type RareEventEstimate struct {
+	Estimate      float64
+	Low           float64
+	High          float64
+	Hits          int
+	EffectiveRuns float64
+}
+
+// rareEventTally adds up the weighted contributions of tilted runs to the
+// estimate of a rare event's probability.
+type rareEventTally struct {
+	sum        float64
+	sumSquares float64
+	hits       int
+}
+
+func (t *rareEventTally) Add(value float64) {
+	if value == 0 {
+		return
+	}
+	t.hits += 1
+	t.sum += value
+	t.sumSquares += value * value
+}
+
+func (t rareEventTally) Estimate(runs int) RareEventEstimate {
+	n := float64(runs)
+	estimate := t.sum / n
+	result := RareEventEstimate{Estimate: estimate, Hits: t.hits}
+	if runs > 1 {
+		variance := (t.sumSquares/n - estimate*estimate) * n / (n - 1)
+		halfWidth := confidenceZ * math.Sqrt(math.Max(0, variance)/n)
+		result.Low = math.Max(0, estimate-halfWidth)
+		result.High = math.Min(1, estimate+halfWidth)
+	}
+	if t.sumSquares > 0 {
+		result.EffectiveRuns = t.sum * t.sum / t.sumSquares
+	}
+	return result
+}
+
+// EstimateLongShotChances estimates the team's playoff and Cup chances from
+// runs tilted simulations. Each run that makes the playoffs adds its weight
+// to the playoff estimate, and its weight times the team's exact chance of
+// winning the Cup from the bracket that season seeds, with the elos it ends
+// with, to the Cup estimate.
+func EstimateLongShotChances(inputs SimulationInputs, tilt Tilt, runs int) (RareEventEstimate, RareEventEstimate, error) {
+	model := inputs.EloModel()
+	var playoffs, cup rareEventTally
+	for i := 0; i < runs; i++ {
+		simulatedSeason, seasonElos, weight := SimulateTiltedSeason(inputs, tilt)
+		standings := CalculateStandings(&inputs.Teams, &simulatedSeason)
+		if !standings.MadePlayoffs(tilt.Team) {
+			continue
+		}
+		playoffs.Add(weight)
+
+		bracket, err := SeedBracket(standings, inputs.Teams)
+		if err != nil {
+			return RareEventEstimate{}, RareEventEstimate{}, err
+		}
+		if odds, ok := bracket.AdvancementOdds(model, seasonElos)[tilt.Team]; ok {
+			cup.Add(weight * odds.Rounds[playoffRounds-1])
+		}
+	}
+	return playoffs.Estimate(runs), cup.Estimate(runs), nil
+}
+
+func RunLongShot(team string, runs int, strength float64, rivalStrength float64) error {
+	seed := time.Now().Unix()
+	rand.Seed(seed)
+	fmt.Printf("using seed %d\n", seed)
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	teamAbbr := ""
+	for abbr := range inputs.Teams {
+		if strings.EqualFold(abbr, team) {
+			teamAbbr = abbr
+		}
+	}
+	if teamAbbr == "" {
+		return fmt.Errorf("unknown team %s", team)
+	}
+
+	tilt := Tilt{
+		Team:          teamAbbr,
+		Conference:    inputs.Teams[teamAbbr].Conference.Name,
+		Strength:      strength,
+		RivalStrength: rivalStrength,
+	}
+	estimate, cupEstimate, err := EstimateLongShotChances(inputs, tilt, runs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s playoff chance: %.3g [%.3g-%.3g]\n", teamAbbr, estimate.Estimate, estimate.Low, estimate.High)
+	fmt.Printf("made the playoffs in %d of %d tilted runs, worth %.0f untilted runs that made it\n", estimate.Hits, runs, estimate.EffectiveRuns)
+	fmt.Printf("%s Cup chance: %.3g [%.3g-%.3g]\n", teamAbbr, cupEstimate.Estimate, cupEstimate.Low, cupEstimate.High)
+	if estimate.Hits == 0 {
+		fmt.Print("no tilted run made the playoffs; try a stronger --tilt\n")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// longShotTeams is the test conference plus W1 from another conference.
+func longShotTeams() map[string]NHLTeamJSON {
+	teams := testConferenceTeams()
+	var west NHLTeamJSON
+	west.Abbreviation = "W1"
+	west.Division.Name = "Pacific"
+	west.Conference.Name = "Western"
+	teams["W1"] = west
+	return teams
+}
+
+func TestTiltHomeWinProbability(t *testing.T) {
+	teams := longShotTeams()
+	logistic := func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+	tilt := Tilt{Team: "A5", Conference: "Eastern", Strength: 1, RivalStrength: 0.5}
+	tests := []struct {
+		home string
+		away string
+		want float64
+	}{
+		{"A5", "A1", logistic(1)},
+		{"A1", "A5", logistic(-1)},
+		// a rival losing to the other conference helps
+		{"A1", "W1", logistic(-0.5)},
+		{"W1", "M1", logistic(0.5)},
+		// rivals playing each other are left alone
+		{"A1", "M1", 0.5},
+	}
+	for _, test := range tests {
+		game := NHLGameCSVRow{HomeTeam: test.home, AwayTeam: test.away}
+		if got := tilt.HomeWinProbability(game, 0.5, teams); math.Abs(got-test.want) > testTolerance {
+			t.Errorf("%s at %s: tilted to %f, want %f", test.away, test.home, got, test.want)
+		}
+		if got := (Tilt{Team: "A5", Conference: "Eastern"}).HomeWinProbability(game, 0.3, teams); got != 0.3 {
+			t.Errorf("%s at %s: untilted %f, want 0.3", test.away, test.home, got)
+		}
+	}
+}
+
+func TestSimulateTiltedSeasonWeights(t *testing.T) {
+	teams := longShotTeams()
+	elos := map[string]float64{"A5": 1450, "A1": 1550, "W1": 1500}
+	season := []NHLGameCSVRow{
+		{Status: "Final", HomeTeam: "A1", AwayTeam: "A5", HomeScore: 4, AwayScore: 1},
+		{Status: "Preview", HomeTeam: "A5", AwayTeam: "A1"},
+	}
+	inputs := SimulationInputs{Elos: elos, Season: season, Teams: teams}
+	_, homeWinPct := inputs.EloModel().GameWinProbability(season[1], elos, &teams)
+
+	untilted := Tilt{Team: "A5", Conference: "Eastern"}
+	tilt := Tilt{Team: "A5", Conference: "Eastern", Strength: 1.5}
+	tiltedWinPct := tilt.HomeWinProbability(season[1], homeWinPct, teams)
+	total := 0.0
+	runs := 2000
+	for i := 0; i < runs; i++ {
+		if _, _, weight := SimulateTiltedSeason(inputs, untilted); weight != 1 {
+			t.Fatalf("untilted run weighted %f, want 1", weight)
+		}
+
+		simulatedSeason, _, weight := SimulateTiltedSeason(inputs, tilt)
+		want := (1 - homeWinPct) / (1 - tiltedWinPct)
+		if simulatedSeason[1].HomeScore > simulatedSeason[1].AwayScore {
+			want = homeWinPct / tiltedWinPct
+		}
+		if math.Abs(weight-want) > testTolerance {
+			t.Fatalf("tilted run weighted %f, want the likelihood ratio %f", weight, want)
+		}
+		total += weight
+	}
+	// the weights undo the tilt, so on average they come to 1
+	if mean := total / float64(runs); math.Abs(mean-1) > 0.05 {
+		t.Errorf("mean weight %f, want about 1", mean)
+	}
+	if elos["A5"] != 1450 {
+		t.Errorf("tilted runs changed the input elos")
+	}
+}
+
+func TestRareEventTallyEstimate(t *testing.T) {
+	var tally rareEventTally
+	for _, value := range []float64{0, 0.5, 0, 0, 0.25, 0, 0, 0, 0, 0} {
+		tally.Add(value)
+	}
+	estimate := tally.Estimate(10)
+	if estimate.Hits != 2 || math.Abs(estimate.Estimate-0.075) > testTolerance {
+		t.Errorf("estimate %+v, want 0.075 from 2 hits", estimate)
+	}
+	// sample variance of the ten values, 0.3125/10 - 0.075^2 scaled by 10/9
+	halfWidth := confidenceZ * math.Sqrt((0.03125-0.075*0.075)*10/9/10)
+	if math.Abs(estimate.High-(0.075+halfWidth)) > testTolerance || estimate.Low != 0 {
+		t.Errorf("interval [%f, %f], want [0, %f]", estimate.Low, estimate.High, 0.075+halfWidth)
+	}
+	if want := 0.75 * 0.75 / 0.3125; math.Abs(estimate.EffectiveRuns-want) > testTolerance {
+		t.Errorf("effective runs %f, want %f", estimate.EffectiveRuns, want)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+	"gopkg.in/yaml.v3"
+)
+
+const draftPicksFile = "data/draft_picks.csv"
+
+// LotteryRules are the draft lottery's odds table and limits. Odds are each
+// non-playoff team's percentage chance of winning a draw, worst team first.
+// Each draw awards the best pick not yet taken, but a winner can't move up
+// more than MaxJump spots: it takes the spot MaxJump ahead of where it would
+// have picked instead, and the drawn pick goes to the worst team left. A team
+// that already won MaxWins draws in the WinWindow years up to Year can't win.
+type LotteryRules struct {
+	Year      int          `yaml:"year"`
+	Odds      []float64    `yaml:"odds"`
+	Draws     int          `yaml:"draws"`
+	MaxJump   int          `yaml:"max_jump"`
+	MaxWins   int          `yaml:"max_wins"`
+	WinWindow int          `yaml:"win_window"`
+	PastWins  []LotteryWin `yaml:"past_wins"`
+}
+
+type LotteryWin struct {
+	Team string `yaml:"team"`
+	Year int    `yaml:"year"`
+}
+
+// DefaultLotteryRules returns the lottery format used since 2022 for the
+// current season's draft, with no past wins.
+func DefaultLotteryRules() LotteryRules {
+	year, _ := strconv.Atoi(currentSeason[4:])
+	return LotteryRules{
+		Year:      year,
+		Odds:      []float64{18.5, 13.5, 11.5, 9.5, 8.5, 7.5, 6.5, 6.0, 5.0, 3.5, 3.0, 2.5, 2.0, 1.5, 0.5, 0.5},
+		Draws:     2,
+		MaxJump:   10,
+		MaxWins:   2,
+		WinWindow: 5,
+	}
+}
+
+// LoadLotteryRules reads lottery rules from a yaml file, keeping the default
+// for anything the file leaves out.
+func LoadLotteryRules(path string) (LotteryRules, error) {
+	rules := DefaultLotteryRules()
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := yaml.Unmarshal(contents, &rules); err != nil {
+		return rules, err
+	}
+
+	if len(rules.Odds) == 0 {
+		return rules, fmt.Errorf("invalid lottery rules %s: no odds", path)
+	}
+	for _, odds := range rules.Odds {
+		if odds < 0 {
+			return rules, fmt.Errorf("invalid lottery rules %s: negative odds %g", path, odds)
+		}
+	}
+	if rules.Draws < 0 || rules.MaxJump < 0 || rules.MaxWins < 0 || rules.WinWindow < 0 {
+		return rules, fmt.Errorf("invalid lottery rules %s: draws and limits can't be negative", path)
+	}
+	return rules, nil
+}
+
+// canWin reports whether the team hasn't hit the limit on lottery wins.
+func (r LotteryRules) canWin(team string) bool {
+	if r.MaxWins == 0 {
+		return true
+	}
+	wins := 0
+	for _, win := range r.PastWins {
+		if win.Team == team && win.Year < r.Year && win.Year > r.Year-r.WinWindow {
+			wins += 1
+		}
+	}
+	return wins < r.MaxWins
+}
+
+func (r LotteryRules) odds(position int) float64 {
+	if position < len(r.Odds) {
+		return r.Odds[position]
+	}
+	return 0
+}
+
+// PickProbabilities works out each team's chance of each pick from every
+// possible outcome of the draws, given the non-playoff teams worst first.
+// picks[i][j] is the chance the i-th team picks j+1-th. Draws that land on an
+// ineligible team or one whose pick is already settled are redrawn.
+func (r LotteryRules) PickProbabilities(order []string) [][]float64 {
+	picks := make([][]float64, len(order))
+	eligible := make([]bool, len(order))
+	for i, team := range order {
+		picks[i] = make([]float64, len(order))
+		eligible[i] = r.canWin(team)
+	}
+
+	// placed[i] is the pick the i-th team has been settled into, or 0
+	placed := make([]int, len(order))
+	taken := make([]bool, len(order)+1)
+	place := func(i int, pick int) {
+		placed[i] = pick
+		taken[pick] = true
+	}
+	unplace := func(i int) {
+		taken[placed[i]] = false
+		placed[i] = 0
+	}
+
+	var draw func(draws int, probability float64)
+	draw = func(draws int, probability float64) {
+		total := 0.0
+		for i := range order {
+			if eligible[i] && placed[i] == 0 {
+				total += r.odds(i)
+			}
+		}
+		if draws == r.Draws || total == 0 {
+			// everyone left picks in order
+			pick := 1
+			for i := range order {
+				if placed[i] > 0 {
+					picks[i][placed[i]-1] += probability
+					continue
+				}
+				for taken[pick] {
+					pick += 1
+				}
+				picks[i][pick-1] += probability
+				pick += 1
+			}
+			return
+		}
+
+		pick := 1
+		for taken[pick] {
+			pick += 1
+		}
+		for i := range order {
+			if !eligible[i] || placed[i] != 0 || r.odds(i) == 0 {
+				continue
+			}
+			if r.MaxJump > 0 && i+1-r.MaxJump > pick {
+				// moves up as far as it can, and the drawn pick goes to the
+				// worst team left
+				place(i, i+1-r.MaxJump)
+				worst := 0
+				for placed[worst] != 0 {
+					worst += 1
+				}
+				place(worst, pick)
+				draw(draws+1, probability*r.odds(i)/total)
+				unplace(worst)
+				unplace(i)
+				continue
+			}
+			place(i, pick)
+			draw(draws+1, probability*r.odds(i)/total)
+			unplace(i)
+		}
+	}
+	draw(0, 1)
+	return picks
+}
+
+// NonPlayoffOrder returns the teams that missed the playoffs, worst first.
+func NonPlayoffOrder(standings Standings) []string {
+	playoffTeams := standings.PlayoffTeams()
+	order := []string{}
+	for i := len(standings.League) - 1; i >= 0; i-- {
+		if team := standings.League[i].Team; !playoffTeams[team] {
+			order = append(order, team)
+		}
+	}
+	return order
+}
+
+// LotteryOrders tallies how often each non-playoff order came up, keyed by
+// the teams worst first and joined with commas. The lottery odds only depend
+// on the order, so they're worked out once per order after the runs instead
+// of once per season.
+type LotteryOrders map[string]float64
+
+// TallyLotteryOrders adds a simulated season's non-playoff order.
+func TallyLotteryOrders(orders LotteryOrders, standings Standings, weight float64) {
+	orders[strings.Join(NonPlayoffOrder(standings), ",")] += weight
+}
+
+// DraftPicks tallies how often each team ended up with each draft pick after
+// the lottery. Playoff teams' picks aren't tallied.
+type DraftPicks map[string]Histogram
+
+// DraftPicks runs the lottery on every tallied order and adds every team's
+// chance of each pick.
+func (orders LotteryOrders) DraftPicks(rules LotteryRules) DraftPicks {
+	draftPicks := make(DraftPicks)
+	for key, weight := range orders {
+		order := strings.Split(key, ",")
+		for i, probabilities := range rules.PickProbabilities(order) {
+			if _, ok := draftPicks[order[i]]; !ok {
+				draftPicks[order[i]] = make(Histogram)
+			}
+			for j, probability := range probabilities {
+				if probability > 0 {
+					draftPicks[order[i]][j+1] += weight * probability
+				}
+			}
+		}
+	}
+	return draftPicks
+}
+
+func PrintDraftPicks(draftPicks DraftPicks, total float64) {
+	teams := []string{}
+	for team := range draftPicks {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return draftPicks[teams[i]].Total() > draftPicks[teams[j]].Total()
+	})
+
+	fmt.Print("draft picks after the lottery:\n")
+	for _, team := range teams {
+		histogram := draftPicks[team]
+		fmt.Printf("%s: %.1f%% out of the playoffs, median pick %d if so:", team, 100*histogram.Total()/total, histogram.Percentile(0.5))
+		for _, pick := range histogram.Values() {
+			fmt.Printf(" #%d %.1f%%", pick, 100*histogram[pick]/total)
+		}
+		fmt.Print("\n")
+	}
+}
+
+// DraftPickRow is a team's chance of one draft pick, out of every run
+// including the ones it made the playoffs.
+type DraftPickRow struct {
+	Team        string  `csv:"team"`
+	Pick        int     `csv:"pick"`
+	Probability float64 `csv:"probability"`
+}
+
+// WriteDraftPicks replaces the draft picks file with the latest run's draft
+// picks, normalized to probabilities.
+func WriteDraftPicks(draftPicks DraftPicks, total float64) error {
+	rows := []DraftPickRow{}
+	for team, histogram := range draftPicks {
+		for _, pick := range histogram.Values() {
+			rows = append(rows, DraftPickRow{Team: team, Pick: pick, Probability: histogram[pick] / total})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Team != rows[j].Team {
+			return rows[i].Team < rows[j].Team
+		}
+		return rows[i].Pick < rows[j].Pick
+	})
+
+	draftPicksCSV, err := os.OpenFile(draftPicksFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer draftPicksCSV.Close()
+
+	return gocsv.MarshalFile(&rows, draftPicksCSV)
+}
+
+func LoadDraftPicks() (DraftPicks, error) {
+	draftPicksCSV, err := os.Open(draftPicksFile)
+	if err != nil {
+		return nil, err
+	}
+	defer draftPicksCSV.Close()
+
+	rows := []*DraftPickRow{}
+	if err := gocsv.UnmarshalFile(draftPicksCSV, &rows); err != nil {
+		return nil, err
+	}
+
+	draftPicks := make(DraftPicks)
+	for _, row := range rows {
+		if _, ok := draftPicks[row.Team]; !ok {
+			draftPicks[row.Team] = make(Histogram)
+		}
+		draftPicks[row.Team][row.Pick] = row.Probability
+	}
+	return draftPicks, nil
+}
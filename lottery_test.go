@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func lotteryOrder(teams int) []string {
+	order := []string{}
+	for i := 0; i < teams; i++ {
+		order = append(order, fmt.Sprintf("T%d", i))
+	}
+	return order
+}
+
+func TestPickProbabilitiesSumToOne(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules LotteryRules
+		teams int
+	}{
+		{"default", DefaultLotteryRules(), 16},
+		{"fewer teams than odds", DefaultLotteryRules(), 12},
+		{"no draws", LotteryRules{Odds: []float64{50, 30, 20}}, 3},
+		{"past winner", LotteryRules{Year: 2023, Odds: DefaultLotteryRules().Odds, Draws: 2, MaxJump: 10, MaxWins: 2, WinWindow: 5,
+			PastWins: []LotteryWin{{Team: "T1", Year: 2020}, {Team: "T1", Year: 2022}}}, 16},
+	}
+	for _, test := range tests {
+		picks := test.rules.PickProbabilities(lotteryOrder(test.teams))
+		for i := range picks {
+			row, column := 0.0, 0.0
+			for j := range picks {
+				row += picks[i][j]
+				column += picks[j][i]
+			}
+			if math.Abs(row-1) > testTolerance {
+				t.Errorf("%s: team %d's picks sum to %f", test.name, i, row)
+			}
+			if math.Abs(column-1) > testTolerance {
+				t.Errorf("%s: pick %d's teams sum to %f", test.name, i+1, column)
+			}
+		}
+	}
+}
+
+func TestPickProbabilities(t *testing.T) {
+	rules := DefaultLotteryRules()
+	picks := rules.PickProbabilities(lotteryOrder(16))
+
+	// the 12th worst team and up can't jump more than 10 spots, so when they
+	// win the first draw the first pick goes to the worst team instead
+	tests := []struct {
+		team int
+		pick int
+		want float64
+	}{
+		{0, 1, 0.255},
+		{0, 3, 0.55675},
+		{0, 4, 0},
+		{10, 1, 0.03},
+		{11, 1, 0},
+		{15, 1, 0},
+		{15, 5, 0},
+	}
+	for _, test := range tests {
+		if got := picks[test.team][test.pick-1]; math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("team %d pick %d: %f, want %f", test.team, test.pick, got, test.want)
+		}
+	}
+
+	for i := range picks {
+		for j := 0; j < i-rules.MaxJump; j++ {
+			if picks[i][j] != 0 {
+				t.Errorf("team %d picks %d with chance %f, more than %d spots up", i, j+1, picks[i][j], rules.MaxJump)
+			}
+		}
+		// a team can drop at most one spot per draw
+		for j := i + rules.Draws + 1; j < len(picks); j++ {
+			if picks[i][j] != 0 {
+				t.Errorf("team %d picks %d with chance %f, more than %d spots down", i, j+1, picks[i][j], rules.Draws)
+			}
+		}
+	}
+}
+
+func TestPickProbabilitiesPastWins(t *testing.T) {
+	rules := DefaultLotteryRules()
+	rules.Year = 2023
+	rules.PastWins = []LotteryWin{{Team: "T1", Year: 2020}, {Team: "T1", Year: 2022}, {Team: "T2", Year: 2018}, {Team: "T2", Year: 2022}}
+	picks := rules.PickProbabilities(lotteryOrder(16))
+
+	if picks[1][0] != 0 {
+		t.Errorf("team with two recent wins picks first with chance %f", picks[1][0])
+	}
+	if picks[2][0] == 0 {
+		t.Error("team whose older win is outside the window can't pick first")
+	}
+}
+
+func TestLotteryOrdersDraftPicks(t *testing.T) {
+	// A4, M4 and A5 swap around below the playoff line
+	standingsWith := func(nonPlayoff ...string) Standings {
+		standings := testStandings(map[string]int{}, []string{"M5"})
+		for _, team := range append([]string{"A1", "A2", "A3", "M1", "M2", "M3", "M5"}, nonPlayoff...) {
+			standings.League = append(standings.League, &NHLSeasonStats{Team: team})
+		}
+		return standings
+	}
+	orders := make(LotteryOrders)
+	TallyLotteryOrders(orders, standingsWith("A4", "M4", "A5"), 1)
+	TallyLotteryOrders(orders, standingsWith("A4", "M4", "A5"), 2)
+	TallyLotteryOrders(orders, standingsWith("M4", "A4", "A5"), 1)
+	want := LotteryOrders{"A5,M4,A4": 3, "A5,A4,M4": 1}
+	if !reflect.DeepEqual(orders, want) {
+		t.Fatalf("orders %v, want %v", orders, want)
+	}
+
+	rules := LotteryRules{Odds: []float64{60, 40}, Draws: 1}
+	draftPicks := orders.DraftPicks(rules)
+	wantPicks := DraftPicks{
+		"A5": {1: 3*0.6 + 0.6, 2: 3*0.4 + 0.4},
+		"M4": {1: 3 * 0.4, 2: 3 * 0.6, 3: 1},
+		"A4": {1: 0.4, 2: 0.6, 3: 3},
+	}
+	for team, histogram := range wantPicks {
+		for pick, weight := range histogram {
+			if math.Abs(draftPicks[team][pick]-weight) > testTolerance {
+				t.Errorf("%s pick %d: %f, want %f", team, pick, draftPicks[team][pick], weight)
+			}
+		}
+		if math.Abs(draftPicks[team].Total()-histogram.Total()) > testTolerance {
+			t.Errorf("%s picks %v, want %v", team, draftPicks[team], histogram)
+		}
+	}
+}
@@ -5,13 +5,81 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"time"
 )
 
 func main() {
 	// subcommands
 	genPreseasonElo := flag.NewFlagSet("gen-preseason-elo", flag.ExitOnError)
 	updateSeason := flag.NewFlagSet("update-season", flag.ExitOnError)
+	updateSeasonModel := playoffModelFlags(updateSeason)
 	simulate := flag.NewFlagSet("simulate", flag.ExitOnError)
+	simulateScenario := simulate.String("scenario", "", "yaml file of what-if results, win probabilities and elo adjustments")
+	simulateExact := simulate.Bool("exact", false, "enumerate every outcome of the remaining games instead of sampling, falling back to sampling if goal differential would break a tie")
+	simulateExactLimit := simulate.Int("exact-limit", 100000, "enumerate automatically when there are at most this many outcome combinations left")
+	simulatePrecision := simulate.String("precision", "", "keep running until the playoff odds of teams near the line are this precise, e.g. 0.1%")
+	simulateTimeBudget := simulate.Duration("time-budget", 0, "keep running until this much time has passed, e.g. 30s")
+	simulateLottery := simulate.String("lottery", "", "yaml file of draft lottery odds, rules and past wins, the current rules if empty")
+	backfill := flag.NewFlagSet("backfill", flag.ExitOnError)
+	backfillRuns := backfill.Int("runs", 10000, "number of simulations to run for each game day")
+	history := flag.NewFlagSet("history", flag.ExitOnError)
+	historyTeam := history.String("team", "", "team abbreviation to show odds history for")
+	historyMovers := history.Int("movers", 5, "number of biggest movers since the previous run to show")
+	chart := flag.NewFlagSet("chart", flag.ExitOnError)
+	chartOut := chart.String("out", "charts", "directory to write svg charts to")
+	report := flag.NewFlagSet("report", flag.ExitOnError)
+	reportOut := report.String("out", "site", "directory to write the html report to")
+	rooting := flag.NewFlagSet("rooting", flag.ExitOnError)
+	rootingTeam := rooting.String("team", "", "team abbreviation to build the rooting guide for")
+	rootingDays := rooting.Int("days", 3, "number of days of upcoming games to consider")
+	rootingRuns := rooting.Int("runs", 100000, "number of simulations to run")
+	games := flag.NewFlagSet("games", flag.ExitOnError)
+	gamesDate := games.String("date", time.Now().Format("2006-01-02"), "date to show games for")
+	clinch := flag.NewFlagSet("clinch", flag.ExitOnError)
+	clinch.Usage = func() {
+		fmt.Fprint(clinch.Output(), "Usage of clinch:\n"+
+			"  shows every team's clinch and elimination marks and magic and tragic numbers.\n"+
+			"  The marks are conservative: each rival is bounded on its own, ignoring games\n"+
+			"  between rivals and tiebreaks past regulation wins, so a mark is never wrong\n"+
+			"  but can come a game or two after the team really clinched or was eliminated.\n")
+		clinch.PrintDefaults()
+	}
+	tonight := flag.NewFlagSet("tonight", flag.ExitOnError)
+	tonightDate := tonight.String("date", time.Now().Format("2006-01-02"), "date of the games to enumerate")
+	tonightMaxGames := tonight.Int("max-games", 8, "most games to enumerate the outcomes of for a single team")
+	compare := flag.NewFlagSet("compare", flag.ExitOnError)
+	compareScenario := compare.String("scenario", "", "yaml file of what-if results for the variant")
+	compareK := compare.Float64("k", DefaultEloModel.K, "elo K factor for the variant")
+	compareHomeIce := compare.Float64("home-ice", DefaultEloModel.HomeIce, "home ice advantage in elo points for the variant")
+	compareRuns := compare.Int("runs", 10000, "number of paired simulations to run")
+	compareSeed := compare.Int64("seed", 0, "seed for the shared random streams, the current time if 0")
+	longShot := flag.NewFlagSet("longshot", flag.ExitOnError)
+	longShotTeam := longShot.String("team", "", "team abbreviation to estimate the playoff chance of")
+	longShotRuns := longShot.Int("runs", 100000, "number of tilted simulations to run")
+	longShotTilt := longShot.Float64("tilt", 1.5, "how far to tilt the team's games toward it, in logits")
+	longShotRivalTilt := longShot.Float64("rival-tilt", 0, "how far to tilt conference rivals' games against the other conference away from them, in logits")
+	needed := flag.NewFlagSet("needed", flag.ExitOnError)
+	neededTeam := needed.String("team", "", "team abbreviation to find the points needed for")
+	neededTarget := needed.String("target", "50%", "playoff chance to reach, e.g. 90%")
+	neededRuns := needed.Int("runs", 100000, "number of simulations to run")
+	series := flag.NewFlagSet("series", flag.ExitOnError)
+	seriesHome := series.String("home", "", "abbreviation of the team with home ice")
+	seriesAway := series.String("away", "", "abbreviation of the team without home ice")
+	seriesState := series.String("state", "", "wins so far with the home ice team's first, e.g. 3-1")
+	seriesModel := playoffModelFlags(series)
+	playoffs := flag.NewFlagSet("playoffs", flag.ExitOnError)
+	playoffsModel := playoffModelFlags(playoffs)
+	predict := flag.NewFlagSet("predict", flag.ExitOnError)
+	predictDate := predict.String("date", time.Now().Format("2006-01-02"), "date to predict games for")
+	predictFormat := predict.String("format", "table", "output format, table or json")
+	predictModel := playoffModelFlags(predict)
+	scores := flag.NewFlagSet("scores", flag.ExitOnError)
+	scoresHome := scores.String("home", "", "abbreviation of the home team")
+	scoresAway := scores.String("away", "", "abbreviation of the away team")
+	scoresActual := scores.Bool("actual", false, "compare the expected and actual score frequencies of this season's finished games instead")
+	serve := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveAddr := serve.String("addr", ":8080", "address to listen on")
+	serveReload := serve.Duration("reload", 30*time.Second, "how often to check the data directory for changes")
 
 	if len(os.Args) < 2 {
 		fmt.Println("gen-preseason-elo or update-season command is required")
@@ -25,6 +93,38 @@ func main() {
 		updateSeason.Parse(os.Args[2:])
 	case "simulate":
 		simulate.Parse(os.Args[2:])
+	case "backfill":
+		backfill.Parse(os.Args[2:])
+	case "history":
+		history.Parse(os.Args[2:])
+	case "chart":
+		chart.Parse(os.Args[2:])
+	case "report":
+		report.Parse(os.Args[2:])
+	case "serve":
+		serve.Parse(os.Args[2:])
+	case "rooting":
+		rooting.Parse(os.Args[2:])
+	case "games":
+		games.Parse(os.Args[2:])
+	case "clinch":
+		clinch.Parse(os.Args[2:])
+	case "tonight":
+		tonight.Parse(os.Args[2:])
+	case "compare":
+		compare.Parse(os.Args[2:])
+	case "longshot":
+		longShot.Parse(os.Args[2:])
+	case "needed":
+		needed.Parse(os.Args[2:])
+	case "series":
+		series.Parse(os.Args[2:])
+	case "playoffs":
+		playoffs.Parse(os.Args[2:])
+	case "predict":
+		predict.Parse(os.Args[2:])
+	case "scores":
+		scores.Parse(os.Args[2:])
 	default:
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -33,9 +133,47 @@ func main() {
 	if genPreseasonElo.Parsed() {
 		doGenPreseasonElo()
 	} else if updateSeason.Parsed() {
-		doUpdateSeason()
+		doUpdateSeason(updateSeasonModel())
 	} else if simulate.Parsed() {
-		doSimulation()
+		doSimulation(*simulateScenario, *simulateExact, *simulateExactLimit, *simulatePrecision, *simulateTimeBudget, *simulateLottery)
+	} else if backfill.Parsed() {
+		doBackfill(*backfillRuns)
+	} else if history.Parsed() {
+		doHistory(*historyTeam, *historyMovers)
+	} else if chart.Parsed() {
+		doChart(*chartOut)
+	} else if report.Parsed() {
+		doReport(*reportOut)
+	} else if serve.Parsed() {
+		doServe(*serveAddr, *serveReload)
+	} else if rooting.Parsed() {
+		doRooting(*rootingTeam, *rootingDays, *rootingRuns)
+	} else if games.Parsed() {
+		doGames(*gamesDate)
+	} else if clinch.Parsed() {
+		doClinch()
+	} else if tonight.Parsed() {
+		doTonight(*tonightDate, *tonightMaxGames)
+	} else if compare.Parsed() {
+		doCompare(CompareOptions{
+			ScenarioPath: *compareScenario,
+			K:            *compareK,
+			HomeIce:      *compareHomeIce,
+			Runs:         *compareRuns,
+			Seed:         *compareSeed,
+		})
+	} else if longShot.Parsed() {
+		doLongShot(*longShotTeam, *longShotRuns, *longShotTilt, *longShotRivalTilt)
+	} else if needed.Parsed() {
+		doPointsNeeded(*neededTeam, *neededTarget, *neededRuns)
+	} else if series.Parsed() {
+		doSeries(*seriesHome, *seriesAway, *seriesState, seriesModel())
+	} else if playoffs.Parsed() {
+		doPlayoffs(playoffsModel())
+	} else if predict.Parsed() {
+		doPredict(*predictDate, *predictFormat, predictModel())
+	} else if scores.Parsed() {
+		doScores(*scoresHome, *scoresAway, *scoresActual)
 	}
 }
 
@@ -66,16 +204,185 @@ func doGenPreseasonElo() {
 	WritePreseasonElos(currentElos)
 }
 
-func doUpdateSeason() {
-	if err := UpdateNHLSeason(); err != nil {
+// playoffModelFlags adds flags for the playoff parameters of the elo model to
+// a subcommand, returning the model they describe once it's parsed.
+func playoffModelFlags(flags *flag.FlagSet) func() EloModel {
+	k := flags.Float64("playoff-k", DefaultEloModel.PlayoffK, "elo K factor for playoff games")
+	homeIce := flags.Float64("playoff-home-ice", DefaultEloModel.PlayoffHomeIce, "home ice advantage in elo points for playoff games")
+	multiplier := flags.Float64("playoff-multiplier", DefaultEloModel.PlayoffMultiplier, "how much to multiply elo differences by in playoff games")
+	return func() EloModel {
+		model := DefaultEloModel
+		model.PlayoffK = *k
+		model.PlayoffHomeIce = *homeIce
+		model.PlayoffMultiplier = *multiplier
+		return model
+	}
+}
+
+func doUpdateSeason(model EloModel) {
+	if err := UpdateNHLSeason(model); err != nil {
 		fmt.Printf("could not update season: %s", err)
 		os.Exit(1)
 	}
 }
 
-func doSimulation() {
-	if err := RunSimulation(); err != nil {
+func doSimulation(scenarioPath string, exact bool, exactLimit int, precisionValue string, timeBudget time.Duration, lotteryPath string) {
+	if exact && (precisionValue != "" || timeBudget != 0) {
+		fmt.Println("--exact can't be combined with --precision or --time-budget")
+		os.Exit(1)
+	}
+	precision, err := ParsePrecision(precisionValue)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	options := SimulationOptions{
+		ScenarioPath: scenarioPath,
+		Exact:        exact,
+		ExactLimit:   exactLimit,
+		Precision:    precision,
+		TimeBudget:   timeBudget,
+		LotteryPath:  lotteryPath,
+	}
+	if err := RunSimulation(options); err != nil {
 		fmt.Printf("could not run simulation: %s", err)
 		os.Exit(1)
 	}
 }
+
+func doBackfill(runs int) {
+	if err := RunBackfill(runs); err != nil {
+		fmt.Printf("could not run backfill: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doHistory(team string, numMovers int) {
+	if err := ShowOddsHistory(team, numMovers); err != nil {
+		fmt.Printf("could not show history: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doChart(outDir string) {
+	if err := WriteEloCharts(outDir); err != nil {
+		fmt.Printf("could not write charts: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doReport(outDir string) {
+	if err := WriteReport(outDir); err != nil {
+		fmt.Printf("could not write report: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doServe(addr string, reloadInterval time.Duration) {
+	if err := RunServer(addr, reloadInterval); err != nil {
+		fmt.Printf("could not run server: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doRooting(team string, days int, runs int) {
+	if team == "" {
+		fmt.Println("--team is required")
+		os.Exit(1)
+	}
+	if err := RunRootingGuide(team, days, runs); err != nil {
+		fmt.Printf("could not build rooting guide: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doGames(date string) {
+	if err := ShowGames(date); err != nil {
+		fmt.Printf("could not show games: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doClinch() {
+	if err := RunClinch(); err != nil {
+		fmt.Printf("could not calculate clinch status: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doTonight(date string, maxGames int) {
+	if err := RunTonight(date, maxGames); err != nil {
+		fmt.Printf("could not enumerate clinching scenarios: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doCompare(options CompareOptions) {
+	if err := RunCompare(options); err != nil {
+		fmt.Printf("could not compare simulations: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doLongShot(team string, runs int, tilt float64, rivalTilt float64) {
+	if team == "" {
+		fmt.Println("--team is required")
+		os.Exit(1)
+	}
+	if err := RunLongShot(team, runs, tilt, rivalTilt); err != nil {
+		fmt.Printf("could not estimate long shot odds: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doPointsNeeded(team string, targetValue string, runs int) {
+	if team == "" {
+		fmt.Println("--team is required")
+		os.Exit(1)
+	}
+	target, err := parseProbability("target", targetValue)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := RunPointsNeeded(team, target, runs); err != nil {
+		fmt.Printf("could not find points needed: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doSeries(home string, away string, state string, model EloModel) {
+	if home == "" || away == "" {
+		fmt.Println("--home and --away are required")
+		os.Exit(1)
+	}
+	if err := RunSeries(home, away, state, model); err != nil {
+		fmt.Printf("could not calculate series odds: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doPlayoffs(model EloModel) {
+	if err := RunPlayoffs(model); err != nil {
+		fmt.Printf("could not calculate playoff odds: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doPredict(date string, format string, model EloModel) {
+	if err := RunPredict(date, format, model); err != nil {
+		fmt.Printf("could not predict games: %s", err)
+		os.Exit(1)
+	}
+}
+
+func doScores(home string, away string, actual bool) {
+	if !actual && (home == "" || away == "") {
+		fmt.Println("--home and --away are required")
+		os.Exit(1)
+	}
+	if err := RunScores(home, away, actual); err != nil {
+		fmt.Printf("could not calculate score probabilities: %s", err)
+		os.Exit(1)
+	}
+}
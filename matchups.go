@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gocarina/gocsv"
+)
+
+const matchupsFile = "data/matchups.csv"
+
+// Pairing is a playoff series, with the team that has home ice first. Division
+// is the side of the bracket the series is on. Seeds are where each team
+// finished on that side: division winner 1, then the division's 2 and 3 seeds,
+// and 4 for the wild card.
+type Pairing struct {
+	Division string
+	Home     string
+	Away     string
+	HomeSeed int
+	AwaySeed int
+}
+
+// wildCardSeed is the seed of a wild card on its side of the bracket
+const wildCardSeed = 4
+
+// FirstRoundPairings pairs each division's 2 and 3 seeds, and each division
+// winner with a wild card: the conference's better division winner plays the
+// 2nd wild card and the other plays the 1st. Home ice goes to the higher seed,
+// so a division winner always hosts its wild card and the 2 seed always hosts
+// the 3 seed, whatever their records.
+func FirstRoundPairings(standings Standings, teams map[string]NHLTeamJSON) []Pairing {
+	divisionWinners := make(map[string][]string)
+	divisions := []string{}
+	for division, seeds := range standings.DivisionSeeds {
+		divisions = append(divisions, division)
+		if len(seeds) == 0 {
+			continue
+		}
+		conference := teams[seeds[0]].Conference.Name
+		divisionWinners[conference] = append(divisionWinners[conference], seeds[0])
+	}
+	sort.Strings(divisions)
+
+	pairings := []Pairing{}
+	for _, division := range divisions {
+		seeds := standings.DivisionSeeds[division]
+		if len(seeds) < 3 {
+			continue
+		}
+		winner := seeds[0]
+		conference := teams[winner].Conference.Name
+		wildCards := standings.WildCards[conference]
+		winners := divisionWinners[conference]
+		if len(wildCards) == 2 && len(winners) == 2 {
+			best := winners[0]
+			if standings.Ranks[winners[1]].League < standings.Ranks[best].League {
+				best = winners[1]
+			}
+			wildCard := wildCards[0]
+			if winner == best {
+				wildCard = wildCards[1]
+			}
+			pairings = append(pairings, Pairing{Division: division, Home: winner, Away: wildCard, HomeSeed: 1, AwaySeed: wildCardSeed})
+		}
+		pairings = append(pairings, Pairing{Division: division, Home: seeds[1], Away: seeds[2], HomeSeed: 2, AwaySeed: 3})
+	}
+	return pairings
+}
+
+// Matchups tallies how often each pair of teams met in the first round, keyed
+// by the team with home ice and then its opponent.
+type Matchups map[string]map[string]float64
+
+func (m Matchups) Add(pairing Pairing, weight float64) {
+	if _, ok := m[pairing.Home]; !ok {
+		m[pairing.Home] = make(map[string]float64)
+	}
+	m[pairing.Home][pairing.Away] += weight
+}
+
+// TallyMatchups adds the first-round pairings of a simulated season.
+func TallyMatchups(matchups Matchups, standings Standings, teams map[string]NHLTeamJSON, weight float64) {
+	for _, pairing := range FirstRoundPairings(standings, teams) {
+		matchups.Add(pairing, weight)
+	}
+}
+
+// Opponent is how often a team met an opponent in the first round, and how
+// often it had home ice when it did.
+type Opponent struct {
+	Team    string
+	Chance  float64
+	HomeIce float64
+}
+
+// Opponents returns the team's possible first-round opponents, most likely
+// first, with chances out of total.
+func (m Matchups) Opponents(team string, total float64) []Opponent {
+	opponents := make(map[string]*Opponent)
+	opponent := func(abbr string) *Opponent {
+		if _, ok := opponents[abbr]; !ok {
+			opponents[abbr] = &Opponent{Team: abbr}
+		}
+		return opponents[abbr]
+	}
+	for away, weight := range m[team] {
+		opponent(away).Chance += weight / total
+		opponent(away).HomeIce += weight / total
+	}
+	for home, aways := range m {
+		if weight, ok := aways[team]; ok {
+			opponent(home).Chance += weight / total
+		}
+	}
+
+	sorted := []Opponent{}
+	for _, o := range opponents {
+		sorted = append(sorted, *o)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Chance != sorted[j].Chance {
+			return sorted[i].Chance > sorted[j].Chance
+		}
+		return sorted[i].Team < sorted[j].Team
+	})
+	return sorted
+}
+
+func PrintMatchups(matchups Matchups, teams map[string]NHLTeamJSON, total float64) {
+	abbrs := []string{}
+	for abbr := range teams {
+		abbrs = append(abbrs, abbr)
+	}
+	sort.Strings(abbrs)
+
+	fmt.Print("first-round opponents (chance, with home ice):\n")
+	for _, abbr := range abbrs {
+		opponents := matchups.Opponents(abbr, total)
+		if len(opponents) == 0 {
+			continue
+		}
+		fmt.Printf("%s:", abbr)
+		for _, o := range opponents {
+			fmt.Printf(" %s %.1f%% (%.1f%%)", o.Team, 100*o.Chance, 100*o.HomeIce)
+		}
+		fmt.Print("\n")
+	}
+}
+
+// MatchupRow is the chance of one first-round pairing, with the team that has
+// home ice first.
+type MatchupRow struct {
+	Home        string  `csv:"home"`
+	Away        string  `csv:"away"`
+	Probability float64 `csv:"probability"`
+}
+
+// WriteMatchups replaces the matchups file with the latest run's first-round
+// pairings, normalized to probabilities.
+func WriteMatchups(matchups Matchups, total float64) error {
+	rows := []MatchupRow{}
+	for home, aways := range matchups {
+		for away, weight := range aways {
+			rows = append(rows, MatchupRow{Home: home, Away: away, Probability: weight / total})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Home != rows[j].Home {
+			return rows[i].Home < rows[j].Home
+		}
+		return rows[i].Away < rows[j].Away
+	})
+
+	matchupsCSV, err := os.OpenFile(matchupsFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer matchupsCSV.Close()
+
+	return gocsv.MarshalFile(&rows, matchupsCSV)
+}
+
+func LoadMatchups() (Matchups, error) {
+	matchupsCSV, err := os.Open(matchupsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer matchupsCSV.Close()
+
+	rows := []*MatchupRow{}
+	if err := gocsv.UnmarshalFile(matchupsCSV, &rows); err != nil {
+		return nil, err
+	}
+
+	matchups := make(Matchups)
+	for _, row := range rows {
+		matchups.Add(Pairing{Home: row.Home, Away: row.Away}, row.Probability)
+	}
+	return matchups, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFirstRoundPairings(t *testing.T) {
+	tests := []struct {
+		name        string
+		leagueRanks map[string]int
+		wildCards   []string
+		want        []Pairing
+	}{
+		{
+			name:        "better division winner plays the 2nd wild card",
+			leagueRanks: map[string]int{"A1": 1, "M1": 2, "A2": 3, "M2": 4, "A3": 5, "M3": 6, "A4": 7, "M4": 8},
+			wildCards:   []string{"A4", "M4"},
+			want: []Pairing{
+				{Division: "Atlantic", Home: "A1", Away: "M4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Atlantic", Home: "A2", Away: "A3", HomeSeed: 2, AwaySeed: 3},
+				{Division: "Metropolitan", Home: "M1", Away: "A4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Metropolitan", Home: "M2", Away: "M3", HomeSeed: 2, AwaySeed: 3},
+			},
+		},
+		{
+			name:        "the other division winner can be better",
+			leagueRanks: map[string]int{"M1": 1, "A1": 2, "A2": 3, "M2": 4, "A3": 5, "M3": 6, "A4": 7, "M4": 8},
+			wildCards:   []string{"A4", "M4"},
+			want: []Pairing{
+				{Division: "Atlantic", Home: "A1", Away: "A4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Atlantic", Home: "A2", Away: "A3", HomeSeed: 2, AwaySeed: 3},
+				{Division: "Metropolitan", Home: "M1", Away: "M4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Metropolitan", Home: "M2", Away: "M3", HomeSeed: 2, AwaySeed: 3},
+			},
+		},
+		{
+			name:        "higher seeds host even with worse records",
+			leagueRanks: map[string]int{"A4": 1, "M1": 2, "A3": 3, "M3": 4, "A1": 5, "M2": 6, "A2": 7, "M4": 8},
+			wildCards:   []string{"A4", "M4"},
+			want: []Pairing{
+				{Division: "Atlantic", Home: "A1", Away: "A4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Atlantic", Home: "A2", Away: "A3", HomeSeed: 2, AwaySeed: 3},
+				{Division: "Metropolitan", Home: "M1", Away: "M4", HomeSeed: 1, AwaySeed: wildCardSeed},
+				{Division: "Metropolitan", Home: "M2", Away: "M3", HomeSeed: 2, AwaySeed: 3},
+			},
+		},
+		{
+			name:        "no wild cards yet",
+			leagueRanks: map[string]int{"A1": 1, "M1": 2, "A2": 3, "M2": 4, "A3": 5, "M3": 6},
+			wildCards:   []string{},
+			want: []Pairing{
+				{Division: "Atlantic", Home: "A2", Away: "A3", HomeSeed: 2, AwaySeed: 3},
+				{Division: "Metropolitan", Home: "M2", Away: "M3", HomeSeed: 2, AwaySeed: 3},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := FirstRoundPairings(testStandings(test.leagueRanks, test.wildCards), testConferenceTeams())
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchupsOpponents(t *testing.T) {
+	matchups := make(Matchups)
+	matchups.Add(Pairing{Home: "A1", Away: "A4"}, 3)
+	matchups.Add(Pairing{Home: "A1", Away: "M4"}, 1)
+	matchups.Add(Pairing{Home: "M1", Away: "A4"}, 2)
+
+	want := []Opponent{
+		{Team: "A1", Chance: 0.3, HomeIce: 0},
+		{Team: "M1", Chance: 0.2, HomeIce: 0},
+	}
+	if got := matchups.Opponents("A4", 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("opponents of A4: got %+v, want %+v", got, want)
+	}
+	want = []Opponent{
+		{Team: "A4", Chance: 0.3, HomeIce: 0.3},
+		{Team: "M4", Chance: 0.1, HomeIce: 0.1},
+	}
+	if got := matchups.Opponents("A1", 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("opponents of A1: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math/rand"
+
+	exprand "golang.org/x/exp/rand"
+)
+
+// EloModel holds the tunable parameters of the elo model: the K factor that
+// scales how far elos move after a game and the home ice advantage in elo
+// points. Playoff games use their own K factor and home ice, and multiply the
+// elo difference by PlayoffMultiplier since favorites win more often in the
+// playoffs.
+type EloModel struct {
+	K                 float64
+	HomeIce           float64
+	PlayoffK          float64
+	PlayoffHomeIce    float64
+	PlayoffMultiplier float64
+}
+
+var DefaultEloModel = EloModel{K: 6, HomeIce: 50, PlayoffK: 6, PlayoffHomeIce: 50, PlayoffMultiplier: 1.25}
+
+// KFor is the K factor for the game.
+func (m EloModel) KFor(game NHLGameCSVRow) float64 {
+	if game.IsPlayoff == 1 {
+		return m.PlayoffK
+	}
+	return m.K
+}
+
+// HomeIceFor is the home ice advantage for the game, if it's played in the
+// home team's arena.
+func (m EloModel) HomeIceFor(game NHLGameCSVRow) float64 {
+	if game.IsPlayoff == 1 {
+		return m.PlayoffHomeIce
+	}
+	return m.HomeIce
+}
+
+// PlayoffEloDiff is the elo difference for a playoff game between two teams,
+// the first of them at home.
+func (m EloModel) PlayoffEloDiff(homeElo float64, awayElo float64) float64 {
+	return (homeElo + m.PlayoffHomeIce - awayElo) * m.PlayoffMultiplier
+}
+
+// RandomStreams gives every game of every run its own random number stream,
+// keyed by the seed, the run index and the GamePK, so two variants of a
+// simulation draw the same numbers for the same game no matter how many draws
+// earlier games took.
+type RandomStreams struct {
+	Seed uint64
+}
+
+func (r *RandomStreams) ForGame(run int, gamePK int64) *exprand.Rand {
+	// splitmix64 finalizer so nearby runs and games get unrelated streams
+	mix := func(x uint64) uint64 {
+		x += 0x9e3779b97f4a7c15
+		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+		return x ^ (x >> 31)
+	}
+	return exprand.New(exprand.NewSource(mix(mix(r.Seed^mix(uint64(run))) ^ uint64(gamePK))))
+}
+
+// GameSimulator simulates games with an elo model, drawing from Rand or, when
+// it is nil, from the global random sources.
+type GameSimulator struct {
+	Model EloModel
+	Rand  *exprand.Rand
+}
+
+func (g GameSimulator) float64() float64 {
+	if g.Rand == nil {
+		return rand.Float64()
+	}
+	return g.Rand.Float64()
+}
+
+func (g GameSimulator) intn(n int) int {
+	if g.Rand == nil {
+		return rand.Intn(n)
+	}
+	return g.Rand.Intn(n)
+}
+
+// source is the source for gonum distributions, nil for their global one.
+func (g GameSimulator) source() exprand.Source {
+	if g.Rand == nil {
+		return nil
+	}
+	return g.Rand
+}
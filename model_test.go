@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEloModelPlayoffParameters(t *testing.T) {
+	model := EloModel{K: 6, HomeIce: 50, PlayoffK: 10, PlayoffHomeIce: 30, PlayoffMultiplier: 1.25}
+	regular := NHLGameCSVRow{HomeTeam: "A1", AwayTeam: "A2"}
+	playoff := NHLGameCSVRow{HomeTeam: "A1", AwayTeam: "A2", IsPlayoff: 1}
+
+	if k := model.KFor(regular); k != 6 {
+		t.Errorf("regular season K %f, want 6", k)
+	}
+	if k := model.KFor(playoff); k != 10 {
+		t.Errorf("playoff K %f, want 10", k)
+	}
+	if homeIce := model.HomeIceFor(regular); homeIce != 50 {
+		t.Errorf("regular season home ice %f, want 50", homeIce)
+	}
+	if homeIce := model.HomeIceFor(playoff); homeIce != 30 {
+		t.Errorf("playoff home ice %f, want 30", homeIce)
+	}
+
+	// home ice is added before the multiplier, for either team at home
+	if diff := model.PlayoffEloDiff(1550, 1500); diff != 100 {
+		t.Errorf("playoff elo diff %f, want (50+30)*1.25", diff)
+	}
+	if diff := model.PlayoffEloDiff(1500, 1550); diff != -25 {
+		t.Errorf("playoff elo diff %f, want (-50+30)*1.25", diff)
+	}
+
+	// a playoff game in the home team's arena matches the series math
+	teams := testConferenceTeams()
+	elos := map[string]float64{"A1": 1550, "A2": 1500}
+	eloDiff, homeWinPct := model.GameWinProbability(playoff, elos, &teams)
+	if eloDiff != model.PlayoffEloDiff(1550, 1500) || math.Abs(homeWinPct-WinProbability(100)) > testTolerance {
+		t.Errorf("playoff game elo diff %f and home win %f, want %f and %f", eloDiff, homeWinPct, model.PlayoffEloDiff(1550, 1500), WinProbability(100))
+	}
+	if eloDiff, _ := model.GameWinProbability(regular, elos, &teams); eloDiff != 100 {
+		t.Errorf("regular season elo diff %f, want 50+50", eloDiff)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// points totals reached in fewer runs than this are too noisy to condition on
+const minConditionalRuns = 10
+
+// PointsOutcome tallies a team's playoff appearances across the simulation
+// runs in which it earned a given number of additional points.
+type PointsOutcome struct {
+	Points   int
+	Runs     int
+	Playoffs int
+}
+
+func (o PointsOutcome) PlayoffChance() float64 {
+	if o.Runs == 0 {
+		return 0
+	}
+	return float64(o.Playoffs) / float64(o.Runs)
+}
+
+// SimulatePointsOutcomes runs one batch of simulations and buckets the team's
+// playoff appearances by how many points it earned over the rest of the
+// season, fewest points first.
+func SimulatePointsOutcomes(inputs SimulationInputs, team string, runs int) []PointsOutcome {
+	currentPoints := CalculateSeasonStats(&inputs.Teams, &inputs.Season)[team].Points
+
+	outcomes := make(map[int]*PointsOutcome)
+	RunSimulations(inputs, runs, func(simulatedSeason []NHLGameCSVRow, standings Standings) {
+		points := 0
+		for _, stats := range standings.League {
+			if stats.Team == team {
+				points = stats.Points - currentPoints
+			}
+		}
+		outcome, ok := outcomes[points]
+		if !ok {
+			outcome = &PointsOutcome{Points: points}
+			outcomes[points] = outcome
+		}
+		outcome.Runs += 1
+		if standings.MadePlayoffs(team) {
+			outcome.Playoffs += 1
+		}
+	})
+
+	sorted := []PointsOutcome{}
+	for _, outcome := range outcomes {
+		sorted = append(sorted, *outcome)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Points < sorted[j].Points
+	})
+	return sorted
+}
+
+// PointsNeeded finds the additional points at which the team's playoff chance
+// crosses the target, interpolating between the points totals on either side
+// of it. Only totals reached often enough are considered, and the crossing is
+// the lowest total from which every higher total also meets the target.
+// Returns false if the target is never met.
+func PointsNeeded(outcomes []PointsOutcome, target float64) (float64, bool) {
+	considered := []PointsOutcome{}
+	for _, outcome := range outcomes {
+		if outcome.Runs >= minConditionalRuns {
+			considered = append(considered, outcome)
+		}
+	}
+
+	crossing := len(considered)
+	for crossing > 0 && considered[crossing-1].PlayoffChance() >= target {
+		crossing -= 1
+	}
+	if crossing == len(considered) {
+		return 0, false
+	}
+	if crossing == 0 {
+		return float64(considered[0].Points), true
+	}
+
+	below, above := considered[crossing-1], considered[crossing]
+	fraction := (target - below.PlayoffChance()) / (above.PlayoffChance() - below.PlayoffChance())
+	return float64(below.Points) + fraction*float64(above.Points-below.Points), true
+}
+
+func RunPointsNeeded(team string, target float64, runs int) error {
+	seed := time.Now().Unix()
+	rand.Seed(seed)
+	fmt.Printf("using seed %d\n", seed)
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	teamAbbr := ""
+	for abbr := range inputs.Teams {
+		if strings.EqualFold(abbr, team) {
+			teamAbbr = abbr
+		}
+	}
+	if teamAbbr == "" {
+		return fmt.Errorf("unknown team %s", team)
+	}
+
+	currentPoints := 0
+	gamesRemaining := 0
+	for _, record := range ClinchRecords(inputs.Teams, inputs.Season) {
+		if record.Team == teamAbbr {
+			currentPoints = record.Points
+			gamesRemaining = record.GamesRemaining
+		}
+	}
+	fmt.Printf("%s: %d points with %d games left\n", teamAbbr, currentPoints, gamesRemaining)
+
+	outcomes := SimulatePointsOutcomes(inputs, teamAbbr, runs)
+	fmt.Print("playoff chance by points earned the rest of the way:\n")
+	for _, outcome := range outcomes {
+		fmt.Printf("  +%-3d (%3d points) %5.1f%% playoffs (%d runs)\n", outcome.Points, currentPoints+outcome.Points, 100*outcome.PlayoffChance(), outcome.Runs)
+	}
+
+	needed, ok := PointsNeeded(outcomes, target)
+	if !ok {
+		fmt.Printf("%s never reached a %.1f%% playoff chance in %d runs\n", teamAbbr, 100*target, runs)
+		return nil
+	}
+	points := int(math.Ceil(needed))
+	wins := points / 2
+	otLosses := points % 2
+	losses := gamesRemaining - wins - otLosses
+	fmt.Printf("%s needs %.1f more points for a %.1f%% playoff chance: %d points, e.g. going %d-%d-%d (%d points total)\n",
+		teamAbbr, needed, 100*target, points, wins, losses, otLosses, currentPoints+points)
+	return nil
+}
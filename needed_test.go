@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPointsNeeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []PointsOutcome
+		target   float64
+		want     float64
+		wantOK   bool
+	}{
+		{
+			name:     "interpolates between totals",
+			outcomes: []PointsOutcome{{10, 100, 20}, {12, 100, 60}, {14, 100, 90}},
+			target:   0.4,
+			want:     11,
+			wantOK:   true,
+		},
+		{
+			name:     "exactly on a total",
+			outcomes: []PointsOutcome{{10, 100, 20}, {12, 100, 60}, {14, 100, 90}},
+			target:   0.6,
+			want:     12,
+			wantOK:   true,
+		},
+		{
+			name:     "already met at the fewest points",
+			outcomes: []PointsOutcome{{10, 100, 80}, {12, 100, 90}},
+			target:   0.5,
+			want:     10,
+			wantOK:   true,
+		},
+		{
+			name:     "never met",
+			outcomes: []PointsOutcome{{10, 100, 10}, {12, 100, 30}},
+			target:   0.5,
+			wantOK:   false,
+		},
+		{
+			name:     "every higher total has to meet the target",
+			outcomes: []PointsOutcome{{10, 100, 60}, {12, 100, 30}, {14, 100, 80}},
+			target:   0.5,
+			want:     12.8,
+			wantOK:   true,
+		},
+		{
+			name:     "rare totals are ignored",
+			outcomes: []PointsOutcome{{8, minConditionalRuns - 1, minConditionalRuns - 1}, {10, 100, 20}, {12, 100, 60}, {16, 1, 0}},
+			target:   0.4,
+			want:     11,
+			wantOK:   true,
+		},
+		{
+			name:   "no outcomes",
+			target: 0.5,
+			wantOK: false,
+		},
+	}
+	for _, test := range tests {
+		got, ok := PointsNeeded(test.outcomes, test.target)
+		if ok != test.wantOK {
+			t.Errorf("%s: ok %t, want %t", test.name, ok, test.wantOK)
+			continue
+		}
+		if ok && math.Abs(got-test.want) > testTolerance {
+			t.Errorf("%s: %f points, want %f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPlayoffChance(t *testing.T) {
+	tests := []struct {
+		outcome PointsOutcome
+		want    float64
+	}{
+		{PointsOutcome{Points: 4, Runs: 0, Playoffs: 0}, 0},
+		{PointsOutcome{Points: 4, Runs: 4, Playoffs: 1}, 0.25},
+		{PointsOutcome{Points: 4, Runs: 4, Playoffs: 4}, 1},
+	}
+	for _, test := range tests {
+		if got := test.outcome.PlayoffChance(); got != test.want {
+			t.Errorf("%+v: playoff chance %f, want %f", test.outcome, got, test.want)
+		}
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gocarina/gocsv"
 )
@@ -128,10 +128,23 @@ type NHLGameCSVRow struct {
 	AwayTeam    string  `csv:"away_team"`
 	AwayScore   int     `csv:"away_score"`
 	AwayELOPre  float64 `csv:"away_elo_pre"`
-	AwayELOPost float64 `csv:"away_elo_pre"`
+	AwayELOPost float64 `csv:"away_elo_post"`
+	Quality     int     `csv:"quality"`
+	Importance  int     `csv:"importance"`
+	Overall     int     `csv:"overall"`
+	IsPlayoff   int     `csv:"playoff"`
+	Round       int     `csv:"round"`
+	Series      int     `csv:"series"`
 }
 
-func UpdateNHLSeason() error {
+// playoffGamePK reads the round and the series within the round from a playoff
+// game's id, whose last three digits are the round, the series and the game
+// within the series.
+func playoffGamePK(gamePK int64) (int, int) {
+	return int(gamePK/100) % 10, int(gamePK/10) % 10
+}
+
+func UpdateNHLSeason(model EloModel) error {
 	season, err := GetNhlSeason()
 	if err != nil {
 		return err
@@ -153,6 +166,15 @@ func UpdateNHLSeason() error {
 	}
 	fmt.Printf("loaded %d elos\n", len(elos))
 
+	// keep the ratings the simulator gave games before they were played, which
+	// LoadNHLGames reads from the ratings file
+	previousRatings := make(map[int64]NHLGameCSVRow)
+	if previousSeason, previousPlayoffs, err := LoadNHLGames(); err == nil {
+		for _, game := range append(previousSeason, previousPlayoffs...) {
+			previousRatings[game.GamePK] = game
+		}
+	}
+
 	gameRows := []NHLGameCSVRow{}
 	for _, date := range season.Dates {
 		for _, game := range date.Games {
@@ -160,10 +182,6 @@ func UpdateNHLSeason() error {
 			if game.GameType == "PR" {
 				continue
 			}
-			if game.GameType == "P" {
-				// TODO: figure out if I want to handle playoffs
-				continue
-			}
 			var isOT, isShootout int
 			if game.Linescore.CurrentPeriodOrdinal == "OT" {
 				isOT = 1
@@ -186,20 +204,22 @@ func UpdateNHLSeason() error {
 				IsOT:       isOT,
 				IsShootout: isShootout,
 			}
+			if game.GameType == "P" {
+				gameRow.IsPlayoff = 1
+				gameRow.Round, gameRow.Series = playoffGamePK(game.GamePK)
+			}
+			if previous, ok := previousRatings[game.GamePK]; ok {
+				gameRow.Quality = previous.Quality
+				gameRow.Importance = previous.Importance
+				gameRow.Overall = previous.Overall
+			}
 
 			if game.Status.AbstractGameState == "Final" {
 				homeELOPre := elos[homeTeam]
 				awayELOPre := elos[awayTeam]
 
-				eloDiff := homeELOPre - awayELOPre
-
-				if game.Venue.Name == teams[homeTeam].Venue.Name {
-					// 50 points for home ice advantage
-					eloDiff += 50
-				}
-
-				homeWinPct := 1.0 / (math.Pow(10, -eloDiff/400.0) + 1)
-				shift := CalculateEloShift(eloDiff, homeWinPct, &gameRow)
+				eloDiff, homeWinPct := model.GameWinProbability(gameRow, elos, &teams)
+				shift := model.CalculateEloShift(eloDiff, homeWinPct, &gameRow)
 
 				gameRow.HomeELOPre = homeELOPre
 				gameRow.AwayELOPre = awayELOPre
@@ -222,27 +242,74 @@ func UpdateNHLSeason() error {
 		}
 	}
 
-	eloFile, err := os.OpenFile(fmt.Sprintf("data/%s.csv", currentSeason), os.O_WRONLY|os.O_CREATE, os.ModePerm)
+	return WriteNHLSeason(gameRows)
+}
+
+func WriteNHLSeason(season []NHLGameCSVRow) error {
+	seasonFile, err := os.OpenFile(fmt.Sprintf("data/%s.csv", currentSeason), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
 		return err
 	}
+	defer seasonFile.Close()
 
-	err = gocsv.MarshalFile(&gameRows, eloFile)
-	return err
+	return gocsv.MarshalFile(&season, seasonFile)
 }
 
+// LoadNHLSeason returns the regular season games.
 func LoadNHLSeason() ([]NHLGameCSVRow, error) {
-	seasonFile, err := os.OpenFile(fmt.Sprintf("data/%s.csv", currentSeason), os.O_RDONLY, os.ModePerm)
+	season, _, err := LoadNHLGames()
+	return season, err
+}
+
+// LoadNHLGames returns the regular season and playoff games separately.
+func LoadNHLGames() ([]NHLGameCSVRow, []NHLGameCSVRow, error) {
+	contents, err := os.ReadFile(fmt.Sprintf("data/%s.csv", currentSeason))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer seasonFile.Close()
 
-	season := []NHLGameCSVRow{}
+	games := []NHLGameCSVRow{}
 
-	if err := gocsv.UnmarshalFile(seasonFile, &season); err != nil {
-		return nil, err
+	if err := gocsv.UnmarshalBytes(fixLegacySeasonHeader(contents), &games); err != nil {
+		return nil, nil, err
 	}
 
-	return season, nil
+	ratings, err := LoadGameRatings()
+	if err != nil {
+		return nil, nil, err
+	}
+	withGameRatings(games, ratings)
+
+	season := []NHLGameCSVRow{}
+	playoffs := []NHLGameCSVRow{}
+	for _, game := range games {
+		if game.IsPlayoff == 1 {
+			playoffs = append(playoffs, game)
+		} else {
+			season = append(season, game)
+		}
+	}
+	return season, playoffs, nil
+}
+
+// fixLegacySeasonHeader renames the second away_elo_pre column of season files
+// written when AwayELOPost was mistakenly tagged away_elo_pre, so the away
+// team's post-game elo loads as such instead of over its pre-game elo.
+func fixLegacySeasonHeader(contents []byte) []byte {
+	header, rest, _ := strings.Cut(string(contents), "\n")
+	columns := strings.Split(header, ",")
+	awayELOPre := []int{}
+	for i, column := range columns {
+		if column == "away_elo_post" {
+			return contents
+		}
+		if column == "away_elo_pre" {
+			awayELOPre = append(awayELOPre, i)
+		}
+	}
+	if len(awayELOPre) != 2 {
+		return contents
+	}
+	columns[awayELOPre[1]] = "away_elo_post"
+	return []byte(strings.Join(columns, ",") + "\n" + rest)
 }
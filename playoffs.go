@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// playoffRounds is how many rounds it takes to win the Cup
+const playoffRounds = 4
+
+// SeriesState is how many games each team has won in a playoff series so far.
+type SeriesState map[string]int
+
+// PlayoffSeriesStates counts each team's wins in the playoff games played so
+// far, keyed by the two teams in alphabetical order.
+func PlayoffSeriesStates(playoffs []NHLGameCSVRow) map[[2]string]SeriesState {
+	states := make(map[[2]string]SeriesState)
+	for _, game := range playoffs {
+		if game.Status != "Final" {
+			continue
+		}
+		key := seriesKey(game.HomeTeam, game.AwayTeam)
+		if _, ok := states[key]; !ok {
+			states[key] = make(SeriesState)
+		}
+		if game.HomeScore > game.AwayScore {
+			states[key][game.HomeTeam] += 1
+		} else {
+			states[key][game.AwayTeam] += 1
+		}
+	}
+	return states
+}
+
+func seriesKey(a string, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// seededRounds is how many rounds home ice goes by seed rather than record
+const seededRounds = 2
+
+// Bracket is the playoff bracket seeded from the final regular season
+// standings. The first round's series are ordered so that the winners of
+// neighbouring series meet in the next round. Seeds are each team's seed on
+// its side of the bracket, from the first round, and Rounds is the round, from
+// 0, of each series that has started.
+type Bracket struct {
+	FirstRound []Pairing
+	Standings  Standings
+	Seeds      map[string]int
+	States     map[[2]string]SeriesState
+	Rounds     map[[2]string]int
+}
+
+// SeedBracket seeds the bracket from final regular season standings, before
+// any playoff games.
+func SeedBracket(standings Standings, teams map[string]NHLTeamJSON) (Bracket, error) {
+	firstRound := FirstRoundPairings(standings, teams)
+	sort.SliceStable(firstRound, func(i, j int) bool {
+		conferenceI := teams[firstRound[i].Home].Conference.Name
+		conferenceJ := teams[firstRound[j].Home].Conference.Name
+		if conferenceI != conferenceJ {
+			return conferenceI < conferenceJ
+		}
+		return firstRound[i].Division < firstRound[j].Division
+	})
+	if len(firstRound) != 1<<(playoffRounds-1) {
+		return Bracket{}, fmt.Errorf("expected %d first-round series, got %d", 1<<(playoffRounds-1), len(firstRound))
+	}
+
+	seeds := make(map[string]int)
+	for _, pairing := range firstRound {
+		seeds[pairing.Home] = pairing.HomeSeed
+		seeds[pairing.Away] = pairing.AwaySeed
+	}
+	return Bracket{
+		FirstRound: firstRound,
+		Standings:  standings,
+		Seeds:      seeds,
+		States:     make(map[[2]string]SeriesState),
+		Rounds:     make(map[[2]string]int),
+	}, nil
+}
+
+// NewBracket seeds the bracket from the regular season and checks that the
+// first-round playoff games played so far match it.
+func NewBracket(teams map[string]NHLTeamJSON, season []NHLGameCSVRow, playoffs []NHLGameCSVRow) (Bracket, error) {
+	for _, game := range season {
+		if game.Status != "Final" {
+			return Bracket{}, fmt.Errorf("the regular season isn't over, game %d is %s", game.GamePK, game.Status)
+		}
+	}
+
+	bracket, err := SeedBracket(CalculateStandings(&teams, &season), teams)
+	if err != nil {
+		return Bracket{}, err
+	}
+
+	seeded := make(map[[2]string]bool)
+	for _, pairing := range bracket.FirstRound {
+		seeded[seriesKey(pairing.Home, pairing.Away)] = true
+	}
+	for _, game := range playoffs {
+		if game.Round == 1 && !seeded[seriesKey(game.HomeTeam, game.AwayTeam)] {
+			return Bracket{}, fmt.Errorf("first-round game %d between %s and %s doesn't match the bracket from the standings", game.GamePK, game.HomeTeam, game.AwayTeam)
+		}
+		bracket.Rounds[seriesKey(game.HomeTeam, game.AwayTeam)] = game.Round - 1
+	}
+	bracket.States = PlayoffSeriesStates(playoffs)
+	return bracket, nil
+}
+
+// homeIce orders two teams meeting in a round so the one with home ice comes
+// first: the higher seed in the first two rounds, and the team that finished
+// higher in the regular season in the conference final and the Cup final.
+func (b Bracket) homeIce(round int, a string, c string) (string, string) {
+	if round < seededRounds && b.Seeds[c] != b.Seeds[a] {
+		if b.Seeds[c] < b.Seeds[a] {
+			return c, a
+		}
+		return a, c
+	}
+	if b.Standings.Ranks[c].League < b.Standings.Ranks[a].League {
+		return c, a
+	}
+	return a, c
+}
+
+// SeriesWinProbability is the chance higher beats lower from where their
+// series stands, if they have played, with higher having home ice.
+func (b Bracket) SeriesWinProbability(model EloModel, elos map[string]float64, higher string, lower string) float64 {
+	state := b.States[seriesKey(higher, lower)]
+	return model.SeriesProbabilities(elos[higher], elos[lower], state[higher], state[lower]).HigherWins
+}
+
+// PlayoffOdds are a team's chances of winning each round; Rounds[0] is
+// winning the first round and Rounds[playoffRounds-1] the Cup.
+type PlayoffOdds struct {
+	Rounds [playoffRounds]float64
+}
+
+// AdvancementOdds works out every team's chance of winning each round from
+// the series states so far. Each series only depends on who is in it, so the
+// chance of a team coming out of any part of the bracket is exact: its chance
+// of getting there times its chance of beating each possible opponent.
+func (b Bracket) AdvancementOdds(model EloModel, elos map[string]float64) map[string]*PlayoffOdds {
+	odds := make(map[string]*PlayoffOdds)
+
+	// each slot is the chance of each team coming out of that part of the
+	// bracket, starting with the teams in each first-round series
+	slots := []map[string]float64{}
+	for _, pairing := range b.FirstRound {
+		slots = append(slots, map[string]float64{pairing.Home: 1}, map[string]float64{pairing.Away: 1})
+		odds[pairing.Home] = &PlayoffOdds{}
+		odds[pairing.Away] = &PlayoffOdds{}
+	}
+
+	for round := 0; round < playoffRounds; round++ {
+		next := []map[string]float64{}
+		for i := 0; i+1 < len(slots); i += 2 {
+			winners := make(map[string]float64)
+			for a, reachA := range slots[i] {
+				for c, reachC := range slots[i+1] {
+					higher, lower := b.homeIce(round, a, c)
+					p := b.SeriesWinProbability(model, elos, higher, lower)
+					winners[higher] += reachA * reachC * p
+					winners[lower] += reachA * reachC * (1 - p)
+				}
+			}
+			for team, chance := range winners {
+				odds[team].Rounds[round] = chance
+			}
+			next = append(next, winners)
+		}
+		slots = next
+	}
+	return odds
+}
+
+func RunPlayoffs(model EloModel) error {
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	bracket, err := NewBracket(inputs.Teams, inputs.Season, inputs.Playoffs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("series so far:\n")
+	keys := [][2]string{}
+	for key := range bracket.States {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	for _, key := range keys {
+		higher, lower := bracket.homeIce(bracket.Rounds[key], key[0], key[1])
+		state := bracket.States[key]
+		fmt.Printf("  %s %d-%d %s: %.1f%% %s\n", higher, state[higher], state[lower], lower,
+			100*bracket.SeriesWinProbability(model, inputs.Elos, higher, lower), higher)
+	}
+
+	odds := bracket.AdvancementOdds(model, inputs.Elos)
+	teams := []string{}
+	for team := range odds {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		cupI, cupJ := odds[teams[i]].Rounds[playoffRounds-1], odds[teams[j]].Rounds[playoffRounds-1]
+		if cupI != cupJ {
+			return cupI > cupJ
+		}
+		return teams[i] < teams[j]
+	})
+
+	fmt.Printf("%-5s %8s %8s %8s %8s\n", "team", "2nd rd", "conf f", "final", "cup")
+	for _, team := range teams {
+		rounds := odds[team].Rounds
+		fmt.Printf("%-5s %7.1f%% %7.1f%% %7.1f%% %7.1f%%\n", team, 100*rounds[0], 100*rounds[1], 100*rounds[2], 100*rounds[3])
+	}
+	return nil
+}
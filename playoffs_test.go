@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// testBracket is a bracket of teams T0 to T15, paired off in order with the
+// even team the 1 or 2 seed and the odd team the 4 or 3 seed. Each team's
+// league rank follows its number except that T3, a 3 seed, finished first.
+func testBracket() (Bracket, map[string]float64) {
+	bracket := Bracket{
+		Standings: Standings{Ranks: make(map[string]TeamRanks)},
+		Seeds:     make(map[string]int),
+		States:    make(map[[2]string]SeriesState),
+		Rounds:    make(map[[2]string]int),
+	}
+	elos := make(map[string]float64)
+	for i := 0; i < 16; i += 2 {
+		home, away := fmt.Sprintf("T%d", i), fmt.Sprintf("T%d", i+1)
+		homeSeed, awaySeed := 1, 4
+		if i%4 == 2 {
+			homeSeed, awaySeed = 2, 3
+		}
+		bracket.FirstRound = append(bracket.FirstRound, Pairing{Home: home, Away: away, HomeSeed: homeSeed, AwaySeed: awaySeed})
+		bracket.Seeds[home], bracket.Seeds[away] = homeSeed, awaySeed
+		bracket.Standings.Ranks[home] = TeamRanks{League: i + 2}
+		bracket.Standings.Ranks[away] = TeamRanks{League: i + 3}
+		elos[home], elos[away] = 1600-float64(10*i), 1590-float64(10*i)
+	}
+	bracket.Standings.Ranks["T3"] = TeamRanks{League: 1}
+	return bracket, elos
+}
+
+func TestAdvancementOddsSumToOne(t *testing.T) {
+	bracket, elos := testBracket()
+	bracket.States[seriesKey("T4", "T5")] = SeriesState{"T4": 1, "T5": 3}
+	model := EloModel{PlayoffHomeIce: 50, PlayoffMultiplier: 1.25}
+	odds := bracket.AdvancementOdds(model, elos)
+
+	for round := 0; round < playoffRounds; round++ {
+		// each round has half as many winners as the last, one per series
+		total := 0.0
+		for _, teamOdds := range odds {
+			total += teamOdds.Rounds[round]
+		}
+		if want := float64(len(bracket.FirstRound) >> round); math.Abs(total-want) > testTolerance {
+			t.Errorf("round %d winners sum to %f, want %f", round+1, total, want)
+		}
+		// and each part of the bracket sends exactly one team on
+		for start := 0; start < len(bracket.FirstRound); start += 1 << round {
+			part := 0.0
+			for _, pairing := range bracket.FirstRound[start : start+1<<round] {
+				part += odds[pairing.Home].Rounds[round] + odds[pairing.Away].Rounds[round]
+			}
+			if math.Abs(part-1) > testTolerance {
+				t.Errorf("round %d: series from %s sends on %f teams", round+1, bracket.FirstRound[start].Home, part)
+			}
+		}
+	}
+	for team, teamOdds := range odds {
+		for round := 1; round < playoffRounds; round++ {
+			if teamOdds.Rounds[round] > teamOdds.Rounds[round-1]+testTolerance {
+				t.Errorf("%s wins round %d more often than round %d: %v", team, round+1, round, teamOdds.Rounds)
+			}
+		}
+	}
+}
+
+func TestAdvancementOddsDecidedSeries(t *testing.T) {
+	bracket, elos := testBracket()
+	bracket.States[seriesKey("T0", "T1")] = SeriesState{"T0": 1, "T1": 4}
+	bracket.States[seriesKey("T2", "T3")] = SeriesState{"T2": 4, "T3": 2}
+	bracket.States[seriesKey("T1", "T2")] = SeriesState{"T1": 4}
+	bracket.Rounds[seriesKey("T1", "T2")] = 1
+	odds := bracket.AdvancementOdds(EloModel{PlayoffHomeIce: 50, PlayoffMultiplier: 1}, elos)
+
+	tests := []struct {
+		team  string
+		round int
+		want  float64
+	}{
+		{"T1", 0, 1},
+		{"T0", 0, 0},
+		{"T2", 0, 1},
+		{"T3", 0, 0},
+		{"T1", 1, 1},
+		{"T2", 1, 0},
+		{"T0", playoffRounds - 1, 0},
+		{"T2", playoffRounds - 1, 0},
+	}
+	for _, test := range tests {
+		if got := odds[test.team].Rounds[test.round]; math.Abs(got-test.want) > testTolerance {
+			t.Errorf("%s wins round %d with chance %f, want %f", test.team, test.round+1, got, test.want)
+		}
+	}
+	if got := odds["T1"].Rounds[2]; got <= 0 || got >= 1 {
+		t.Errorf("T1 wins its undecided conference final with chance %f", got)
+	}
+}
+
+func TestBracketHomeIce(t *testing.T) {
+	bracket, _ := testBracket()
+	tests := []struct {
+		round  int
+		a      string
+		c      string
+		higher string
+	}{
+		// seeds first, even over a better record
+		{0, "T3", "T2", "T2"},
+		{1, "T0", "T3", "T0"},
+		{1, "T3", "T0", "T0"},
+		// the record breaks equal seeds
+		{1, "T8", "T0", "T0"},
+		// and decides it from the conference final on
+		{2, "T0", "T3", "T3"},
+		{playoffRounds - 1, "T3", "T0", "T3"},
+		{2, "T4", "T9", "T4"},
+	}
+	for _, test := range tests {
+		if higher, lower := bracket.homeIce(test.round, test.a, test.c); higher != test.higher || (lower != test.a && lower != test.c) || lower == higher {
+			t.Errorf("round %d %s and %s: home ice %s over %s, want %s", test.round+1, test.a, test.c, higher, lower, test.higher)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// puckLine is the goal spread the favorite has to cover
+const puckLine = 1.5
+
+// Score is a final score, home team first.
+type Score struct {
+	Home int `json:"home"`
+	Away int `json:"away"`
+}
+
+// GamePrediction is the model's view of a single game before it's played.
+// Expected points are standings points, with a point for losing in overtime,
+// and there are none in playoff games. The favorite covers the puck line by
+// winning by 2 or more.
+type GamePrediction struct {
+	GamePK                   int64   `json:"game_pk"`
+	Date                     string  `json:"date"`
+	Status                   string  `json:"status"`
+	Playoff                  bool    `json:"playoff"`
+	HomeTeam                 string  `json:"home_team"`
+	AwayTeam                 string  `json:"away_team"`
+	HomeWinProbability       float64 `json:"home_win_probability"`
+	AwayWinProbability       float64 `json:"away_win_probability"`
+	OvertimeProbability      float64 `json:"overtime_probability"`
+	HomeExpectedPoints       float64 `json:"home_expected_points"`
+	AwayExpectedPoints       float64 `json:"away_expected_points"`
+	HomeExpectedGoals        float64 `json:"home_expected_goals"`
+	AwayExpectedGoals        float64 `json:"away_expected_goals"`
+	ExpectedTotal            float64 `json:"expected_total"`
+	HomeWinScore             Score   `json:"home_win_score"`
+	AwayWinScore             Score   `json:"away_win_score"`
+	Favorite                 string  `json:"favorite"`
+	FavoriteCoverProbability float64 `json:"favorite_cover_probability"`
+}
+
+func (m EloModel) PredictGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) GamePrediction {
+	eloDiff, homeWinPct := m.GameWinProbability(game, elos, teams)
+	otChance := OvertimeProbability(eloDiff)
+
+	prediction := GamePrediction{
+		GamePK:              game.GamePK,
+		Date:                game.Date,
+		Status:              game.Status,
+		Playoff:             game.IsPlayoff == 1,
+		HomeTeam:            game.HomeTeam,
+		AwayTeam:            game.AwayTeam,
+		HomeWinProbability:  homeWinPct,
+		AwayWinProbability:  1 - homeWinPct,
+		OvertimeProbability: otChance,
+		HomeExpectedPoints:  2*homeWinPct + (1-homeWinPct)*otChance,
+		AwayExpectedPoints:  2*(1-homeWinPct) + homeWinPct*otChance,
+		Favorite:            game.HomeTeam,
+	}
+	if prediction.Playoff {
+		prediction.HomeExpectedPoints, prediction.AwayExpectedPoints = 0, 0
+	}
+	isHomeFavorite := homeWinPct >= 0.5
+	if !isHomeFavorite {
+		prediction.Favorite = game.AwayTeam
+	}
+
+	matrix := NewScoreMatrix(eloDiff, homeWinPct, prediction.Playoff)
+	homeWinBest, awayWinBest := 0.0, 0.0
+	for homeScore := range matrix.Regulation {
+		for awayScore := range matrix.Regulation[homeScore] {
+			score := Score{Home: homeScore, Away: awayScore}
+			prob := matrix.Probability(homeScore, awayScore)
+			prediction.HomeExpectedGoals += prob * float64(score.Home)
+			prediction.AwayExpectedGoals += prob * float64(score.Away)
+			if score.Home > score.Away && prob > homeWinBest {
+				prediction.HomeWinScore, homeWinBest = score, prob
+			}
+			if score.Away > score.Home && prob > awayWinBest {
+				prediction.AwayWinScore, awayWinBest = score, prob
+			}
+			margin := float64(score.Home - score.Away)
+			if !isHomeFavorite {
+				margin = -margin
+			}
+			if margin > puckLine {
+				prediction.FavoriteCoverProbability += prob
+			}
+		}
+	}
+	prediction.ExpectedTotal = prediction.HomeExpectedGoals + prediction.AwayExpectedGoals
+	return prediction
+}
+
+func RunPredict(date string, format string, model EloModel) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %s, expected table or json", format)
+	}
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	// games already played are predicted from the elos going into them
+	predictions := []GamePrediction{}
+	for _, game := range append(inputs.Season, inputs.Playoffs...) {
+		if game.Date != date {
+			continue
+		}
+		elos := inputs.Elos
+		if game.Status == "Final" {
+			elos = map[string]float64{game.HomeTeam: game.HomeELOPre, game.AwayTeam: game.AwayELOPre}
+		}
+		predictions = append(predictions, model.PredictGame(game, elos, &inputs.Teams))
+	}
+	if len(predictions) == 0 {
+		return fmt.Errorf("no games on %s", date)
+	}
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].GamePK < predictions[j].GamePK
+	})
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(predictions)
+	}
+
+	fmt.Printf("games on %s:\n", date)
+	fmt.Printf("%-11s %6s %6s %6s %11s %16s %9s %9s %16s\n",
+		"matchup", "home", "away", "ot", "points", "goals", "home win", "away win", "puck line")
+	for _, p := range predictions {
+		fmt.Printf("%-11s %5.1f%% %5.1f%% %5.1f%% %5.2f-%-5.2f %4.2f-%-4.2f (%4.2f) %9s %9s %-8s %6.1f%%\n",
+			p.AwayTeam+" at "+p.HomeTeam, 100*p.HomeWinProbability, 100*p.AwayWinProbability, 100*p.OvertimeProbability,
+			p.HomeExpectedPoints, p.AwayExpectedPoints, p.HomeExpectedGoals, p.AwayExpectedGoals, p.ExpectedTotal,
+			fmt.Sprintf("%d-%d", p.HomeWinScore.Home, p.HomeWinScore.Away), fmt.Sprintf("%d-%d", p.AwayWinScore.Away, p.AwayWinScore.Home),
+			fmt.Sprintf("%s -%.1f", p.Favorite, puckLine), 100*p.FavoriteCoverProbability)
+	}
+	return nil
+}
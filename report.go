@@ -0,0 +1,425 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ReportOdds are a team's simulated odds as percentages.
+type ReportOdds struct {
+	Playoffs         float64
+	D1Seed           float64
+	D2Seed           float64
+	D3Seed           float64
+	WC1              float64
+	WC2              float64
+	PresidentsTrophy float64
+	ConferenceLeader float64
+	LastOverall      float64
+}
+
+type ReportGame struct {
+	Date     string
+	Opponent string
+	Home     bool
+	WinProb  float64
+}
+
+type ReportTeam struct {
+	Abbr          string
+	Name          string
+	Division      string
+	Conference    string
+	Elo           float64
+	Stats         NHLSeasonStats
+	Odds          ReportOdds
+	Remaining     []ReportGame
+	Distributions []ReportDistribution
+	Opponents     []ReportOpponent
+	DraftPicks    []ReportBucket
+}
+
+// ReportOpponent is a possible first-round opponent, with percentage chances.
+type ReportOpponent struct {
+	Team    string
+	Chance  float64
+	HomeIce float64
+}
+
+// ReportDistribution is one of a team's simulated finishing distributions,
+// with each value's probability as a percentage.
+type ReportDistribution struct {
+	Label       string
+	Percentiles Percentiles
+	Buckets     []ReportBucket
+}
+
+type ReportBucket struct {
+	Value int
+	Pct   float64
+}
+
+type ReportDivision struct {
+	Name  string
+	Teams []*ReportTeam
+}
+
+type ReportConference struct {
+	Name      string
+	Divisions []*ReportDivision
+	WildCard  []*ReportTeam
+	CutLines  []ReportCutLine
+}
+
+// ReportCutLine is the formatted percentiles of one of a conference's cut
+// line stats.
+type ReportCutLine struct {
+	Label       string
+	Percentiles []string
+}
+
+type Report struct {
+	Generated   string
+	Runs        int
+	Seed        int64
+	ThroughDate string
+	Conferences []*ReportConference
+	Teams       []*ReportTeam
+}
+
+// SimulationOutputs are what the latest simulation run wrote besides its odds.
+// Older runs didn't write them, so any may be nil.
+type SimulationOutputs struct {
+	Distributions map[string]TeamDistributions
+	CutLines      ConferenceCutLines
+	Matchups      Matchups
+	DraftPicks    DraftPicks
+}
+
+func LoadSimulationOutputs() SimulationOutputs {
+	var outputs SimulationOutputs
+	var err error
+	if outputs.Distributions, err = LoadDistributions(); err != nil {
+		fmt.Printf("not including distributions: %s\n", err)
+	}
+	if outputs.CutLines, err = LoadCutLines(); err != nil {
+		fmt.Printf("not including cut lines: %s\n", err)
+	}
+	if outputs.Matchups, err = LoadMatchups(); err != nil {
+		fmt.Printf("not including matchups: %s\n", err)
+	}
+	if outputs.DraftPicks, err = LoadDraftPicks(); err != nil {
+		fmt.Printf("not including draft picks: %s\n", err)
+	}
+	return outputs
+}
+
+func WriteReport(outDir string) error {
+	simulationResults, latestRun, err := LatestSimulationResults()
+	if err != nil {
+		return err
+	}
+
+	preseasonElos, err := LoadPreseasonElos()
+	if err != nil {
+		return err
+	}
+
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return err
+	}
+
+	report := BuildReport(CurrentElos(preseasonElos, season), season, teams, simulationResults, latestRun, LoadSimulationOutputs())
+
+	if err := os.MkdirAll(filepath.Join(outDir, "teams"), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := writeReportPage(filepath.Join(outDir, "index.html"), reportIndexTemplate, report); err != nil {
+		return err
+	}
+	for _, team := range report.Teams {
+		page := struct {
+			Report *Report
+			Team   *ReportTeam
+		}{&report, team}
+		if err := writeReportPage(filepath.Join(outDir, "teams", fmt.Sprintf("%s.html", team.Abbr)), reportTeamTemplate, page); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("wrote report for %d teams to %s\n", len(report.Teams), outDir)
+	return nil
+}
+
+// BuildReport combines the current standings, elos and remaining schedule with
+// the results of a simulation run and whatever other outputs it wrote.
+func BuildReport(elos map[string]float64, season []NHLGameCSVRow, teams map[string]NHLTeamJSON, simulationResults map[string]*TeamSimulationResults, run OddsHistoryRow, outputs SimulationOutputs) Report {
+	seasonStats := CalculateSeasonStats(&teams, &season)
+
+	report := Report{
+		Generated:   run.Timestamp,
+		Runs:        run.Runs,
+		Seed:        run.Seed,
+		ThroughDate: run.LastFinalDate,
+	}
+
+	reportTeams := make(map[string]*ReportTeam)
+	for abbr, team := range teams {
+		reportTeam := &ReportTeam{
+			Abbr:       abbr,
+			Name:       team.Name,
+			Division:   team.Division.Name,
+			Conference: team.Conference.Name,
+			Elo:        elos[abbr],
+			Stats:      *seasonStats[abbr],
+		}
+		if results, ok := simulationResults[abbr]; ok && run.Runs > 0 {
+			runs := float64(run.Runs)
+			reportTeam.Odds = ReportOdds{
+				Playoffs:         100. * results.MadePlayoffs / runs,
+				D1Seed:           100. * results.D1Seed / runs,
+				D2Seed:           100. * results.D2Seed / runs,
+				D3Seed:           100. * results.D3Seed / runs,
+				WC1:              100. * results.WC1 / runs,
+				WC2:              100. * results.WC2 / runs,
+				PresidentsTrophy: 100. * results.PresidentsTrophy / runs,
+				ConferenceLeader: 100. * results.ConferenceLeader / runs,
+				LastOverall:      100. * results.LastOverall / runs,
+			}
+		}
+		for _, stat := range distributionStats {
+			histogram, ok := outputs.Distributions[abbr][stat]
+			if !ok {
+				continue
+			}
+			distribution := ReportDistribution{Label: distributionLabels[stat], Percentiles: histogram.Percentiles()}
+			for _, value := range histogram.Values() {
+				distribution.Buckets = append(distribution.Buckets, ReportBucket{Value: value, Pct: 100. * histogram[value] / histogram.Total()})
+			}
+			reportTeam.Distributions = append(reportTeam.Distributions, distribution)
+		}
+		// matchups and draft picks are saved as probabilities
+		for _, pick := range outputs.DraftPicks[abbr].Values() {
+			reportTeam.DraftPicks = append(reportTeam.DraftPicks, ReportBucket{Value: pick, Pct: 100. * outputs.DraftPicks[abbr][pick]})
+		}
+		for _, opponent := range outputs.Matchups.Opponents(abbr, 1) {
+			reportTeam.Opponents = append(reportTeam.Opponents, ReportOpponent{Team: opponent.Team, Chance: 100. * opponent.Chance, HomeIce: 100. * opponent.HomeIce})
+		}
+		reportTeams[abbr] = reportTeam
+		report.Teams = append(report.Teams, reportTeam)
+	}
+
+	for _, game := range season {
+		if game.Status == "Final" {
+			continue
+		}
+		_, homeWinPct := GameWinProbability(game, elos, &teams)
+		if home, ok := reportTeams[game.HomeTeam]; ok {
+			home.Remaining = append(home.Remaining, ReportGame{Date: game.Date, Opponent: game.AwayTeam, Home: true, WinProb: 100. * homeWinPct})
+		}
+		if away, ok := reportTeams[game.AwayTeam]; ok {
+			away.Remaining = append(away.Remaining, ReportGame{Date: game.Date, Opponent: game.HomeTeam, WinProb: 100. * (1 - homeWinPct)})
+		}
+	}
+
+	// the standings' ranks apply every tiebreak, down to head to head and goals
+	standings := CalculateStandings(&teams, &season)
+	sortByStandings := func(reportTeams []*ReportTeam) {
+		sort.Slice(reportTeams, func(i, j int) bool {
+			return standings.Ranks[reportTeams[i].Abbr].League < standings.Ranks[reportTeams[j].Abbr].League
+		})
+	}
+
+	conferences := make(map[string]*ReportConference)
+	divisions := make(map[string]*ReportDivision)
+	sortByStandings(report.Teams)
+	for _, team := range report.Teams {
+		sort.SliceStable(team.Remaining, func(i, j int) bool {
+			return team.Remaining[i].Date < team.Remaining[j].Date
+		})
+
+		conference, ok := conferences[team.Conference]
+		if !ok {
+			conference = &ReportConference{Name: team.Conference}
+			conferences[team.Conference] = conference
+			report.Conferences = append(report.Conferences, conference)
+		}
+		division, ok := divisions[team.Division]
+		if !ok {
+			division = &ReportDivision{Name: team.Division}
+			divisions[team.Division] = division
+			conference.Divisions = append(conference.Divisions, division)
+		}
+		division.Teams = append(division.Teams, team)
+	}
+
+	// the wild card race is everyone outside of the top three in each division
+	for _, conference := range report.Conferences {
+		for _, division := range conference.Divisions {
+			if len(division.Teams) > 3 {
+				conference.WildCard = append(conference.WildCard, division.Teams[3:]...)
+			}
+		}
+		sortByStandings(conference.WildCard)
+		for _, stat := range cutLineStats {
+			if histogram, ok := outputs.CutLines[conference.Name][stat]; ok {
+				conference.CutLines = append(conference.CutLines, ReportCutLine{Label: cutLineLabels[stat], Percentiles: CutLinePercentiles(stat, histogram)})
+			}
+		}
+		sort.Slice(conference.Divisions, func(i, j int) bool {
+			return conference.Divisions[i].Name < conference.Divisions[j].Name
+		})
+	}
+	sort.Slice(report.Conferences, func(i, j int) bool {
+		return report.Conferences[i].Name < report.Conferences[j].Name
+	})
+
+	return report
+}
+
+func writeReportPage(path string, tmpl *template.Template, data interface{}) error {
+	page, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer page.Close()
+
+	return tmpl.Execute(page, data)
+}
+
+var reportFuncs = template.FuncMap{
+	"pct": func(pct float64) string {
+		return fmt.Sprintf("%.1f%%", pct)
+	},
+	"elo": func(elo float64) string {
+		return fmt.Sprintf("%.0f", elo)
+	},
+}
+
+const reportStyle = `<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.cut td { border-bottom: 2px solid #888; }
+.bar { background: #4a7ab5; height: 10px; }
+td.hist { width: 200px; text-align: left; }
+.meta { color: #666; }
+</style>`
+
+var reportIndexTemplate = template.Must(template.New("index").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>NHL playoff odds</title>` + reportStyle + `</head>
+<body>
+<h1>NHL playoff odds</h1>
+<p class="meta">{{.Runs}} simulations (seed {{.Seed}}) run {{.Generated}} through games on {{.ThroughDate}}</p>
+{{range .Conferences}}
+<h2>{{.Name}} Conference</h2>
+{{range .Divisions}}
+<h3>{{.Name}} Division</h3>
+<table>
+<tr><th>Team</th><th>W-L</th><th>OTL</th><th>PTS</th><th>GF-GA</th><th>Elo</th><th>Playoffs</th><th>D1</th><th>D2</th><th>D3</th></tr>
+{{range $i, $team := .Teams}}<tr{{if eq $i 2}} class="cut"{{end}}>
+<td><a href="teams/{{$team.Abbr}}.html">{{$team.Name}}</a></td>
+<td>{{$team.Stats.Wins}}-{{$team.Stats.RegulationLosses}}</td>
+<td>{{$team.Stats.OTLosses}}</td>
+<td>{{$team.Stats.Points}}</td>
+<td>{{$team.Stats.GoalsFor}}-{{$team.Stats.GoalsAgainst}}</td>
+<td>{{elo $team.Elo}}</td>
+<td>{{pct $team.Odds.Playoffs}}</td>
+<td>{{pct $team.Odds.D1Seed}}</td>
+<td>{{pct $team.Odds.D2Seed}}</td>
+<td>{{pct $team.Odds.D3Seed}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+<h3>{{.Name}} Wild Card</h3>
+<table>
+<tr><th>Team</th><th>W-L</th><th>OTL</th><th>PTS</th><th>GF-GA</th><th>Elo</th><th>Playoffs</th><th>WC1</th><th>WC2</th></tr>
+{{range $i, $team := .WildCard}}<tr{{if eq $i 1}} class="cut"{{end}}>
+<td><a href="teams/{{$team.Abbr}}.html">{{$team.Name}}</a></td>
+<td>{{$team.Stats.Wins}}-{{$team.Stats.RegulationLosses}}</td>
+<td>{{$team.Stats.OTLosses}}</td>
+<td>{{$team.Stats.Points}}</td>
+<td>{{$team.Stats.GoalsFor}}-{{$team.Stats.GoalsAgainst}}</td>
+<td>{{elo $team.Elo}}</td>
+<td>{{pct $team.Odds.Playoffs}}</td>
+<td>{{pct $team.Odds.WC1}}</td>
+<td>{{pct $team.Odds.WC2}}</td>
+</tr>
+{{end}}</table>
+{{if .CutLines}}<h3>{{.Name}} Cut Line</h3>
+<table>
+<tr><th></th><th>5th</th><th>25th</th><th>Median</th><th>75th</th><th>95th</th></tr>
+{{range .CutLines}}<tr><td>{{.Label}}</td>{{range .Percentiles}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+var reportTeamTemplate = template.Must(template.New("team").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Team.Name}} playoff odds</title>` + reportStyle + `</head>
+<body>
+<p><a href="../index.html">&larr; all teams</a></p>
+<h1>{{.Team.Name}}</h1>
+<p class="meta">{{.Team.Division}} Division, {{.Team.Conference}} Conference. {{.Report.Runs}} simulations run {{.Report.Generated}} through games on {{.Report.ThroughDate}}</p>
+<table>
+<tr><th>Record</th><th>PTS</th><th>GF-GA</th><th>Elo</th></tr>
+<tr><td>{{.Team.Stats.Wins}}-{{.Team.Stats.RegulationLosses}}-{{.Team.Stats.OTLosses}}</td><td>{{.Team.Stats.Points}}</td><td>{{.Team.Stats.GoalsFor}}-{{.Team.Stats.GoalsAgainst}}</td><td>{{elo .Team.Elo}}</td></tr>
+</table>
+<h2>Playoff odds</h2>
+<table>
+<tr><th>Playoffs</th><th>D1</th><th>D2</th><th>D3</th><th>WC1</th><th>WC2</th></tr>
+<tr><td>{{pct .Team.Odds.Playoffs}}</td><td>{{pct .Team.Odds.D1Seed}}</td><td>{{pct .Team.Odds.D2Seed}}</td><td>{{pct .Team.Odds.D3Seed}}</td><td>{{pct .Team.Odds.WC1}}</td><td>{{pct .Team.Odds.WC2}}</td></tr>
+</table>
+<table>
+<tr><th>Presidents' Trophy</th><th>Conference leader</th><th>Division title</th><th>Last overall</th></tr>
+<tr><td>{{pct .Team.Odds.PresidentsTrophy}}</td><td>{{pct .Team.Odds.ConferenceLeader}}</td><td>{{pct .Team.Odds.D1Seed}}</td><td>{{pct .Team.Odds.LastOverall}}</td></tr>
+</table>
+{{if .Team.Distributions}}<h2>Finishing distributions</h2>
+<table>
+<tr><th></th><th>5th</th><th>25th</th><th>Median</th><th>75th</th><th>95th</th></tr>
+{{range .Team.Distributions}}<tr><td>{{.Label}}</td><td>{{.Percentiles.P5}}</td><td>{{.Percentiles.P25}}</td><td>{{.Percentiles.Median}}</td><td>{{.Percentiles.P75}}</td><td>{{.Percentiles.P95}}</td></tr>
+{{end}}</table>
+{{range .Team.Distributions}}<h3>{{.Label}}</h3>
+<table>
+{{range .Buckets}}<tr><td>{{.Value}}</td><td>{{pct .Pct}}</td><td class="hist"><div class="bar" style="width: {{.Pct}}%"></div></td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Team.DraftPicks}}<h2>Draft lottery</h2>
+<table>
+<tr><th>Pick</th><th>Chance</th><th></th></tr>
+{{range .Team.DraftPicks}}<tr><td>{{.Value}}</td><td>{{pct .Pct}}</td><td class="hist"><div class="bar" style="width: {{.Pct}}%"></div></td></tr>
+{{end}}</table>
+{{end}}
+{{if .Team.Opponents}}<h2>First-round opponents</h2>
+<table>
+<tr><th>Opponent</th><th>Chance</th><th>With home ice</th></tr>
+{{range .Team.Opponents}}<tr><td><a href="{{.Team}}.html">{{.Team}}</a></td><td>{{pct .Chance}}</td><td>{{pct .HomeIce}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Remaining schedule</h2>
+<table>
+<tr><th>Date</th><th>Opponent</th><th>Win probability</th></tr>
+{{range .Team.Remaining}}<tr>
+<td>{{.Date}}</td>
+<td>{{if .Home}}vs{{else}}@{{end}} <a href="{{.Opponent}}.html">{{.Opponent}}</a></td>
+<td>{{pct .WinProb}}</td>
+</tr>
+{{else}}<tr><td colspan="3">No games remaining</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildReportStandingsOrder(t *testing.T) {
+	teams := testConferenceTeams()
+	game := func(home string, away string, homeScore int, awayScore int, isOT int) NHLGameCSVRow {
+		return NHLGameCSVRow{Status: "Final", HomeTeam: home, AwayTeam: away, HomeScore: homeScore, AwayScore: awayScore, IsOT: isOT}
+	}
+	// A1 and A2 both have 4 points and a regulation win, but A2's other two
+	// points came from an overtime win, which breaks the tie. M3 has 4 points
+	// from overtime wins alone.
+	season := []NHLGameCSVRow{
+		game("A1", "M5", 3, 1, 0),
+		game("M3", "A1", 3, 2, 1),
+		game("M3", "A1", 3, 2, 1),
+		game("A2", "M5", 3, 1, 0),
+		game("A2", "M4", 3, 2, 1),
+		game("A3", "A4", 3, 1, 0),
+	}
+	report := BuildReport(map[string]float64{}, season, teams, nil, OddsHistoryRow{}, SimulationOutputs{})
+
+	order := []string{}
+	for _, team := range report.Teams[:3] {
+		order = append(order, team.Abbr)
+	}
+	if want := []string{"A2", "A1", "M3"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("league order %v, want %v", order, want)
+	}
+	for _, conference := range report.Conferences {
+		for _, division := range conference.Divisions {
+			if division.Name != "Atlantic" {
+				continue
+			}
+			order = []string{}
+			for _, team := range division.Teams {
+				order = append(order, team.Abbr)
+			}
+			if want := []string{"A2", "A1", "A3", "A5", "A4"}; !reflect.DeepEqual(order, want) {
+				t.Errorf("Atlantic order %v, want %v", order, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RootingGame tallies a team's playoff appearances across simulation runs,
+// bucketed by how an upcoming game turned out in each run.
+type RootingGame struct {
+	Game     NHLGameCSVRow
+	Runs     [numGameOutcomes]int
+	Playoffs [numGameOutcomes]int
+}
+
+func (g *RootingGame) PlayoffChance(outcome GameOutcome) float64 {
+	if g.Runs[outcome] == 0 {
+		return math.NaN()
+	}
+	return float64(g.Playoffs[outcome]) / float64(g.Runs[outcome])
+}
+
+// Swing is the difference in playoff odds between the best and worst outcome
+// of the game that happened in at least one run.
+func (g *RootingGame) Swing() float64 {
+	best, worst := math.Inf(-1), math.Inf(1)
+	for outcome := GameOutcome(0); outcome < numGameOutcomes; outcome++ {
+		if g.Runs[outcome] == 0 {
+			continue
+		}
+		best = math.Max(best, g.PlayoffChance(outcome))
+		worst = math.Min(worst, g.PlayoffChance(outcome))
+	}
+	if math.IsInf(best, 0) {
+		return 0
+	}
+	return best - worst
+}
+
+// UpcomingGameIndexes returns the indexes in the season of the unplayed games
+// in the days window starting with the first unplayed game.
+func UpcomingGameIndexes(season []NHLGameCSVRow, days int) ([]int, error) {
+	firstDate := ""
+	for _, game := range season {
+		if game.Status != "Final" && (firstDate == "" || game.Date < firstDate) {
+			firstDate = game.Date
+		}
+	}
+	if firstDate == "" {
+		return nil, nil
+	}
+
+	start, err := time.Parse("2006-01-02", firstDate)
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 0, days).Format("2006-01-02")
+
+	indexes := []int{}
+	for i, game := range season {
+		if game.Status != "Final" && game.Date < end {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// SimulateRootingGuide runs one batch of simulations and, for every game in
+// the window, buckets the team's playoff appearances by that game's outcome.
+func SimulateRootingGuide(inputs SimulationInputs, team string, gameIndexes []int, runs int) (float64, []*RootingGame) {
+	rootingGames := []*RootingGame{}
+	for _, i := range gameIndexes {
+		rootingGames = append(rootingGames, &RootingGame{Game: inputs.Season[i]})
+	}
+
+	madePlayoffs := 0
+	RunSimulations(inputs, runs, func(simulatedSeason []NHLGameCSVRow, standings Standings) {
+		made := standings.MadePlayoffs(team)
+		if made {
+			madePlayoffs += 1
+		}
+		for j, i := range gameIndexes {
+			outcome := OutcomeOf(simulatedSeason[i])
+			rootingGames[j].Runs[outcome] += 1
+			if made {
+				rootingGames[j].Playoffs[outcome] += 1
+			}
+		}
+	})
+
+	sort.SliceStable(rootingGames, func(i, j int) bool {
+		return rootingGames[i].Swing() > rootingGames[j].Swing()
+	})
+	return float64(madePlayoffs) / float64(runs), rootingGames
+}
+
+func RunRootingGuide(team string, days int, runs int) error {
+	seed := time.Now().Unix()
+	rand.Seed(seed)
+	fmt.Printf("using seed %d\n", seed)
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	teamAbbr := ""
+	for abbr := range inputs.Teams {
+		if strings.EqualFold(abbr, team) {
+			teamAbbr = abbr
+		}
+	}
+	if teamAbbr == "" {
+		return fmt.Errorf("unknown team %s", team)
+	}
+
+	gameIndexes, err := UpcomingGameIndexes(inputs.Season, days)
+	if err != nil {
+		return err
+	}
+	if len(gameIndexes) == 0 {
+		return fmt.Errorf("no games left to play")
+	}
+
+	playoffChance, rootingGames := SimulateRootingGuide(inputs, teamAbbr, gameIndexes, runs)
+
+	fmt.Printf("%s: %.1f%% playoffs over %d runs\n", teamAbbr, 100*playoffChance, runs)
+	for _, rootingGame := range rootingGames {
+		game := rootingGame.Game
+		fmt.Printf("%s %s at %s (swing %.1f):\n", game.Date, game.AwayTeam, game.HomeTeam, 100*rootingGame.Swing())
+		for outcome := GameOutcome(0); outcome < numGameOutcomes; outcome++ {
+			if rootingGame.Runs[outcome] == 0 {
+				continue
+			}
+			fmt.Printf("  %-24s %5.1f%% playoffs (%d runs)\n", outcome.Describe(game)+":", 100*rootingGame.PlayoffChance(outcome), rootingGame.Runs[outcome])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRootingGameSwing(t *testing.T) {
+	game := &RootingGame{}
+	if swing := game.Swing(); swing != 0 {
+		t.Errorf("swing with no runs %f, want 0", swing)
+	}
+	if chance := game.PlayoffChance(HomeRegulationWin); !math.IsNaN(chance) {
+		t.Errorf("chance with no runs %f, want NaN", chance)
+	}
+
+	game.Runs[HomeRegulationWin], game.Playoffs[HomeRegulationWin] = 40, 30
+	game.Runs[AwayOTWin], game.Playoffs[AwayOTWin] = 10, 2
+	game.Runs[AwayRegulationWin], game.Playoffs[AwayRegulationWin] = 50, 20
+	// outcomes that never happened don't count toward the swing
+	if swing := game.Swing(); math.Abs(swing-(0.75-0.2)) > testTolerance {
+		t.Errorf("swing %f, want %f", swing, 0.75-0.2)
+	}
+}
+
+func TestUpcomingGameIndexes(t *testing.T) {
+	season := []NHLGameCSVRow{
+		{Date: "2023-03-01", Status: "Final"},
+		{Date: "2023-03-03", Status: "Preview"},
+		{Date: "2023-03-02", Status: "Preview"},
+		{Date: "2023-03-04", Status: "Preview"},
+		{Date: "2023-03-02", Status: "Final"},
+	}
+	indexes, err := UpcomingGameIndexes(season, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(indexes, want) {
+		t.Errorf("indexes %v, want %v", indexes, want)
+	}
+	if indexes, err := UpcomingGameIndexes(season[:1], 2); err != nil || indexes != nil {
+		t.Errorf("indexes %v and error %v for a finished season", indexes, err)
+	}
+}
+
+func TestSimulateRootingGuide(t *testing.T) {
+	teams := testConferenceTeams()
+	season := lastNightSeason()
+	elos := make(map[string]float64)
+	for team := range teams {
+		elos[team] = 1500
+	}
+	inputs := SimulationInputs{Elos: elos, Season: season, Teams: teams}
+	gameIndexes := []int{len(season) - 2, len(season) - 1}
+
+	// A5 needs a regulation win over M4 to catch A4 and M4, and then it comes
+	// down to goals
+	runs := 2000
+	playoffChance, rootingGames := SimulateRootingGuide(inputs, "A5", gameIndexes, runs)
+	if rootingGames[0].Game.GamePK != season[gameIndexes[0]].GamePK || rootingGames[0].Game.HomeTeam != "A5" {
+		t.Fatalf("biggest swing %s at %s, want M4 at A5", rootingGames[0].Game.AwayTeam, rootingGames[0].Game.HomeTeam)
+	}
+
+	game := rootingGames[0]
+	total, made := 0, 0
+	for outcome := GameOutcome(0); outcome < numGameOutcomes; outcome++ {
+		total += game.Runs[outcome]
+		made += game.Playoffs[outcome]
+		if outcome != HomeRegulationWin && game.Playoffs[outcome] != 0 {
+			t.Errorf("A5 made the playoffs %d times after %s", game.Playoffs[outcome], outcome.Describe(game.Game))
+		}
+	}
+	if total != runs {
+		t.Errorf("buckets hold %d runs, want %d", total, runs)
+	}
+	if math.Abs(float64(made)/float64(runs)-playoffChance) > testTolerance {
+		t.Errorf("buckets make the playoffs %d times, overall chance %f", made, playoffChance)
+	}
+	if chance := game.PlayoffChance(HomeRegulationWin); chance <= 0 || chance >= 1 {
+		t.Errorf("A5 playoff chance after a regulation win %f, want it left to goals", chance)
+	}
+
+	// the other game doesn't matter to A5 beyond noise
+	other := rootingGames[1]
+	if other.Swing() >= game.Swing() {
+		t.Errorf("A1 and M1's swing %f isn't less than A5 and M4's %f", other.Swing(), game.Swing())
+	}
+}
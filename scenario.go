@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a set of what-if assumptions layered on top of a simulation:
+// fixed results for some games, different win probabilities for others and
+// elo adjustments for teams from a given date.
+type Scenario struct {
+	Name             string                   `yaml:"name"`
+	Results          []ScenarioResult         `yaml:"results"`
+	WinProbabilities []ScenarioWinProbability `yaml:"win_probabilities"`
+	EloAdjustments   []ScenarioEloAdjustment  `yaml:"elo_adjustments"`
+
+	results          map[int64]ScenarioResult
+	winProbabilities map[int64]float64
+}
+
+// ScenarioResult fixes the outcome of a game. Decision is REG, OT or SO and
+// defaults to REG; the score is simulated unless both scores are given.
+type ScenarioResult struct {
+	GamePK    int64  `yaml:"game_pk"`
+	Winner    string `yaml:"winner"`
+	Decision  string `yaml:"decision"`
+	HomeScore *int   `yaml:"home_score"`
+	AwayScore *int   `yaml:"away_score"`
+}
+
+type ScenarioWinProbability struct {
+	GamePK      int64   `yaml:"game_pk"`
+	HomeWinProb float64 `yaml:"home_win_prob"`
+}
+
+// ScenarioEloAdjustment shifts a team's elo for every game played on or after
+// the From date.
+type ScenarioEloAdjustment struct {
+	Team       string  `yaml:"team"`
+	From       string  `yaml:"from"`
+	Adjustment float64 `yaml:"adjustment"`
+}
+
+func LoadScenario(path string, season []NHLGameCSVRow) (*Scenario, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scenario := &Scenario{}
+	if err := yaml.Unmarshal(contents, scenario); err != nil {
+		return nil, err
+	}
+	if scenario.Name == "" {
+		scenario.Name = path
+	}
+
+	if err := scenario.validate(season); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", scenario.Name, err)
+	}
+	return scenario, nil
+}
+
+func (s *Scenario) validate(season []NHLGameCSVRow) error {
+	games := make(map[int64]NHLGameCSVRow)
+	teams := make(map[string]string)
+	for _, game := range season {
+		games[game.GamePK] = game
+		teams[strings.ToUpper(game.HomeTeam)] = game.HomeTeam
+		teams[strings.ToUpper(game.AwayTeam)] = game.AwayTeam
+	}
+
+	s.results = make(map[int64]ScenarioResult)
+	for _, result := range s.Results {
+		game, ok := games[result.GamePK]
+		if !ok {
+			return fmt.Errorf("unknown game %d", result.GamePK)
+		}
+		if game.Status == "Final" {
+			return fmt.Errorf("game %d is already final", result.GamePK)
+		}
+		if strings.EqualFold(result.Winner, game.HomeTeam) {
+			result.Winner = game.HomeTeam
+		} else if strings.EqualFold(result.Winner, game.AwayTeam) {
+			result.Winner = game.AwayTeam
+		} else {
+			return fmt.Errorf("winner %s did not play in game %d (%s at %s)", result.Winner, result.GamePK, game.AwayTeam, game.HomeTeam)
+		}
+		result.Decision = strings.ToUpper(result.Decision)
+		if result.Decision == "" {
+			result.Decision = "REG"
+		}
+		if result.Decision != "REG" && result.Decision != "OT" && result.Decision != "SO" {
+			return fmt.Errorf("decision for game %d must be REG, OT or SO, not %s", result.GamePK, result.Decision)
+		}
+		if (result.HomeScore == nil) != (result.AwayScore == nil) {
+			return fmt.Errorf("game %d needs both a home and away score", result.GamePK)
+		}
+		if result.HomeScore != nil {
+			homeWin := *result.HomeScore > *result.AwayScore
+			if *result.HomeScore == *result.AwayScore || homeWin != (result.Winner == game.HomeTeam) {
+				return fmt.Errorf("score %d-%d for game %d does not match winner %s", *result.HomeScore, *result.AwayScore, result.GamePK, result.Winner)
+			}
+			margin := *result.HomeScore - *result.AwayScore
+			if result.Decision != "REG" && margin != 1 && margin != -1 {
+				return fmt.Errorf("score %d-%d for game %d can't be decided in %s, which is won by one goal", *result.HomeScore, *result.AwayScore, result.GamePK, result.Decision)
+			}
+		}
+		s.results[result.GamePK] = result
+	}
+
+	s.winProbabilities = make(map[int64]float64)
+	for _, prob := range s.WinProbabilities {
+		game, ok := games[prob.GamePK]
+		if !ok {
+			return fmt.Errorf("unknown game %d", prob.GamePK)
+		}
+		if game.Status == "Final" {
+			return fmt.Errorf("game %d is already final", prob.GamePK)
+		}
+		if prob.HomeWinProb < 0 || prob.HomeWinProb > 1 {
+			return fmt.Errorf("home win probability for game %d must be between 0 and 1", prob.GamePK)
+		}
+		s.winProbabilities[prob.GamePK] = prob.HomeWinProb
+	}
+
+	for i, adjustment := range s.EloAdjustments {
+		team, ok := teams[strings.ToUpper(adjustment.Team)]
+		if !ok {
+			return fmt.Errorf("unknown team %s", adjustment.Team)
+		}
+		s.EloAdjustments[i].Team = team
+	}
+	return nil
+}
+
+// EloAdjustment is the total adjustment to a team's elo for a game on the given date.
+func (s *Scenario) EloAdjustment(team string, date string) float64 {
+	adjustment := 0.0
+	for _, eloAdjustment := range s.EloAdjustments {
+		if eloAdjustment.Team == team && date >= eloAdjustment.From {
+			adjustment += eloAdjustment.Adjustment
+		}
+	}
+	return adjustment
+}
+
+// SimulateGame simulates a game honoring the scenario's fixed result or win
+// probability for it, falling back to the regular elo model.
+func (s *Scenario) SimulateGame(simulator GameSimulator, game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) NHLGameCSVRow {
+	homeAdjustment := s.EloAdjustment(game.HomeTeam, game.Date)
+	awayAdjustment := s.EloAdjustment(game.AwayTeam, game.Date)
+	elos[game.HomeTeam] += homeAdjustment
+	elos[game.AwayTeam] += awayAdjustment
+	defer func() {
+		elos[game.HomeTeam] -= homeAdjustment
+		elos[game.AwayTeam] -= awayAdjustment
+	}()
+
+	eloDiff, homeWinPct := simulator.Model.GameWinProbability(game, elos, teams)
+
+	if result, ok := s.results[game.GamePK]; ok {
+		isHomeWin := result.Winner == game.HomeTeam
+		isOT := result.Decision != "REG"
+		isShootout := result.Decision == "SO"
+		var homeScore, awayScore int
+		if result.HomeScore != nil {
+			homeScore, awayScore = *result.HomeScore, *result.AwayScore
+		} else {
+			homeScore, awayScore = simulator.SimulateScore(eloDiff, isHomeWin, isOT)
+		}
+		return simulator.Model.RecordGameResult(game, elos, eloDiff, homeWinPct, homeScore, awayScore, isOT, isShootout)
+	}
+
+	if prob, ok := s.winProbabilities[game.GamePK]; ok {
+		isHomeWin := simulator.float64() < prob
+		return simulator.SimulateGameWithWinner(game, elos, eloDiff, homeWinPct, isHomeWin)
+	}
+
+	return simulator.SimulateGame(game, elos, teams)
+}
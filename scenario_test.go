@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func scenarioSeason() []NHLGameCSVRow {
+	return []NHLGameCSVRow{
+		{GamePK: 1, Date: "2023-03-01", Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 2},
+		{GamePK: 2, Date: "2023-03-02", Status: "Preview", HomeTeam: "A1", AwayTeam: "M1"},
+		{GamePK: 3, Date: "2023-03-03", Status: "Preview", HomeTeam: "M1", AwayTeam: "A2"},
+	}
+}
+
+func loadTestScenario(t *testing.T, contents string) (*Scenario, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadScenario(path, scenarioSeason())
+}
+
+func TestLoadScenarioValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{"unknown game", "results: [{game_pk: 9, winner: A1}]", "unknown game 9"},
+		{"final game", "results: [{game_pk: 1, winner: A1}]", "already final"},
+		{"winner not playing", "results: [{game_pk: 2, winner: A2}]", "did not play"},
+		{"bad decision", "results: [{game_pk: 2, winner: A1, decision: tie}]", "must be REG, OT or SO"},
+		{"one score", "results: [{game_pk: 2, winner: A1, home_score: 3}]", "both a home and away score"},
+		{"score against winner", "results: [{game_pk: 2, winner: M1, home_score: 3, away_score: 1}]", "does not match winner"},
+		{"overtime by two", "results: [{game_pk: 2, winner: A1, decision: OT, home_score: 4, away_score: 2}]", "won by one goal"},
+		{"probability of a final game", "win_probabilities: [{game_pk: 1, home_win_prob: 0.5}]", "already final"},
+		{"probability out of range", "win_probabilities: [{game_pk: 2, home_win_prob: 1.5}]", "between 0 and 1"},
+		{"unknown team", "elo_adjustments: [{team: XYZ, adjustment: 50}]", "unknown team XYZ"},
+	}
+	for _, test := range tests {
+		if _, err := loadTestScenario(t, test.contents); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: error %v, want one containing %q", test.name, err, test.wantErr)
+		}
+	}
+
+	scenario, err := loadTestScenario(t, `
+results: [{game_pk: 2, winner: m1, decision: so, home_score: 2, away_score: 3}]
+win_probabilities: [{game_pk: 3, home_win_prob: 0.25}]
+elo_adjustments: [{team: a2, from: "2023-03-03", adjustment: -25}]
+`)
+	if err != nil {
+		t.Fatalf("valid scenario: %s", err)
+	}
+	if result := scenario.results[2]; result.Winner != "M1" || result.Decision != "SO" {
+		t.Errorf("result normalized to %+v", result)
+	}
+	if scenario.EloAdjustments[0].Team != "A2" {
+		t.Errorf("adjustment team normalized to %s", scenario.EloAdjustments[0].Team)
+	}
+	if !strings.HasSuffix(scenario.Name, "scenario.yaml") {
+		t.Errorf("unnamed scenario named %s, want its path", scenario.Name)
+	}
+}
+
+func TestScenarioEloAdjustment(t *testing.T) {
+	scenario := &Scenario{EloAdjustments: []ScenarioEloAdjustment{
+		{Team: "A1", From: "2023-03-02", Adjustment: 50},
+		{Team: "A1", From: "2023-03-10", Adjustment: -20},
+		{Team: "M1", Adjustment: 10},
+	}}
+	tests := []struct {
+		team string
+		date string
+		want float64
+	}{
+		{"A1", "2023-03-01", 0},
+		{"A1", "2023-03-02", 50},
+		{"A1", "2023-03-09", 50},
+		{"A1", "2023-03-10", 30},
+		{"M1", "2023-01-01", 10},
+		{"A2", "2023-03-10", 0},
+	}
+	for _, test := range tests {
+		if got := scenario.EloAdjustment(test.team, test.date); got != test.want {
+			t.Errorf("%s on %s: adjustment %f, want %f", test.team, test.date, got, test.want)
+		}
+	}
+}
+
+func TestScenarioSimulateGame(t *testing.T) {
+	teams := testConferenceTeams()
+	scenario, err := loadTestScenario(t, `
+results: [{game_pk: 2, winner: M1, decision: OT}]
+elo_adjustments: [{team: A1, from: "2023-03-02", adjustment: 100}]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simulator := GameSimulator{Model: DefaultEloModel}
+	for i := 0; i < 20; i++ {
+		elos := map[string]float64{"A1": 1500, "M1": 1500}
+		game := scenario.SimulateGame(simulator, scenarioSeason()[1], elos, &teams)
+		if game.AwayScore != game.HomeScore+1 || game.IsOT != 1 || game.IsShootout != 0 {
+			t.Fatalf("fixed M1 OT win simulated as %d-%d, ot %d, shootout %d", game.HomeScore, game.AwayScore, game.IsOT, game.IsShootout)
+		}
+		// the adjustment applies to the game but doesn't stick to the elos,
+		// which only trade the game's shift
+		if math.Abs(elos["A1"]+elos["M1"]-3000) > testTolerance || elos["A1"] == 1500 {
+			t.Fatalf("elos after the game %v", elos)
+		}
+	}
+}
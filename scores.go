@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// scores less likely than this aren't worth a row or column in the printed
+// matrix
+const minShownScoreProbability = 0.001
+
+// ScoreGrid is a chance for each final score, indexed by the home team's goals
+// and then the away team's.
+type ScoreGrid [maxLikelyGoals + 1][maxLikelyGoals + 1]float64
+
+func (g ScoreGrid) Total() float64 {
+	total := 0.0
+	for home := range g {
+		for away := range g[home] {
+			total += g[home][away]
+		}
+	}
+	return total
+}
+
+// conditionalScoreProbabilities is the chance of each final score under the
+// same goal model as SimulateScore, given who won and whether it went to
+// overtime: the independent Poisson scores, kept only where the right team won
+// and by exactly one goal in overtime, renormalized.
+func conditionalScoreProbabilities(eloDiff float64, isHomeWin bool, isOT bool) map[Score]float64 {
+	homeLambda, awayLambda := GoalLambdas(eloDiff)
+	homePoisson := distuv.Poisson{Lambda: homeLambda}
+	awayPoisson := distuv.Poisson{Lambda: awayLambda}
+
+	probabilities := make(map[Score]float64)
+	total := 0.0
+	for homeScore := 0; homeScore <= maxLikelyGoals; homeScore++ {
+		for awayScore := 0; awayScore <= maxLikelyGoals; awayScore++ {
+			if (homeScore > awayScore) != isHomeWin || homeScore == awayScore {
+				continue
+			}
+			if isOT && homeScore-awayScore != 1 && awayScore-homeScore != 1 {
+				continue
+			}
+			prob := homePoisson.Prob(float64(homeScore)) * awayPoisson.Prob(float64(awayScore))
+			probabilities[Score{Home: homeScore, Away: awayScore}] = prob
+			total += prob
+		}
+	}
+	for score := range probabilities {
+		probabilities[score] /= total
+	}
+	return probabilities
+}
+
+// ScoreMatrix is the chance of every final score of a game under the model
+// SimulateGame plays it out with, split by whether the game ended in
+// regulation, overtime or a shootout.
+type ScoreMatrix struct {
+	HomeWinProbability  float64
+	OvertimeProbability float64
+	Regulation          ScoreGrid
+	Overtime            ScoreGrid
+	Shootout            ScoreGrid
+}
+
+// NewScoreMatrix works out the score matrix of a game from the elo difference
+// between the teams and the home win probability: the winner, then overtime
+// from the logistic model with half of overtimes going to a shootout, then the
+// Poisson scores kept only where the right team won, by one goal past
+// regulation. Playoff games have no shootouts, so every overtime is played
+// out.
+func NewScoreMatrix(eloDiff float64, homeWinPct float64, isPlayoff bool) ScoreMatrix {
+	otChance := OvertimeProbability(eloDiff)
+	matrix := ScoreMatrix{HomeWinProbability: homeWinPct, OvertimeProbability: otChance}
+	for _, isHomeWin := range []bool{true, false} {
+		winPct := homeWinPct
+		if !isHomeWin {
+			winPct = 1 - homeWinPct
+		}
+		for score, prob := range conditionalScoreProbabilities(eloDiff, isHomeWin, false) {
+			matrix.Regulation[score.Home][score.Away] += winPct * (1 - otChance) * prob
+		}
+		for score, prob := range conditionalScoreProbabilities(eloDiff, isHomeWin, true) {
+			if isPlayoff {
+				matrix.Overtime[score.Home][score.Away] += winPct * otChance * prob
+				continue
+			}
+			matrix.Overtime[score.Home][score.Away] += winPct * otChance / 2 * prob
+			matrix.Shootout[score.Home][score.Away] += winPct * otChance / 2 * prob
+		}
+	}
+	return matrix
+}
+
+// MatchupScoreMatrix is the score matrix for the home team hosting the away
+// team in its own building with their elos as they are.
+func (m EloModel) MatchupScoreMatrix(home string, away string, elos map[string]float64, teams *map[string]NHLTeamJSON) ScoreMatrix {
+	game := NHLGameCSVRow{HomeTeam: home, AwayTeam: away, Venue: (*teams)[home].Venue.Name}
+	eloDiff, homeWinPct := m.GameWinProbability(game, elos, teams)
+	return NewScoreMatrix(eloDiff, homeWinPct, false)
+}
+
+// Probability is the chance of the final score however the game ended.
+func (m ScoreMatrix) Probability(home int, away int) float64 {
+	return m.Regulation[home][away] + m.Overtime[home][away] + m.Shootout[home][away]
+}
+
+// Add adds another matrix's chances, weighted, to this one.
+func (m *ScoreMatrix) Add(other ScoreMatrix, weight float64) {
+	for home := range m.Regulation {
+		for away := range m.Regulation[home] {
+			m.Regulation[home][away] += weight * other.Regulation[home][away]
+			m.Overtime[home][away] += weight * other.Overtime[home][away]
+			m.Shootout[home][away] += weight * other.Shootout[home][away]
+		}
+	}
+}
+
+// ScoreFrequencies adds up the score matrix of every finished game from the
+// elos going into it, next to how often each score actually happened, so the
+// expected and actual counts can be compared. Scores too lopsided for the
+// matrix only count toward the number of games.
+func (m EloModel) ScoreFrequencies(season []NHLGameCSVRow, teams *map[string]NHLTeamJSON) (ScoreMatrix, ScoreMatrix, int) {
+	var expected, actual ScoreMatrix
+	games := 0
+	for _, game := range season {
+		if game.Status != "Final" {
+			continue
+		}
+		games += 1
+		elos := map[string]float64{game.HomeTeam: game.HomeELOPre, game.AwayTeam: game.AwayELOPre}
+		eloDiff, homeWinPct := m.GameWinProbability(game, elos, teams)
+		expected.Add(NewScoreMatrix(eloDiff, homeWinPct, game.IsPlayoff == 1), 1)
+
+		if game.HomeScore > maxLikelyGoals || game.AwayScore > maxLikelyGoals {
+			continue
+		}
+		switch {
+		case game.IsShootout == 1:
+			actual.Shootout[game.HomeScore][game.AwayScore] += 1
+		case game.IsOT == 1:
+			actual.Overtime[game.HomeScore][game.AwayScore] += 1
+		default:
+			actual.Regulation[game.HomeScore][game.AwayScore] += 1
+		}
+	}
+	return expected, actual, games
+}
+
+// shownGoals is the most goals either team scores with a chance worth showing.
+func (m ScoreMatrix) shownGoals() int {
+	shown := 0
+	for home := range m.Regulation {
+		for away := range m.Regulation[home] {
+			if m.Probability(home, away) >= minShownScoreProbability {
+				if home > shown {
+					shown = home
+				}
+				if away > shown {
+					shown = away
+				}
+			}
+		}
+	}
+	return shown
+}
+
+func PrintScoreMatrix(matrix ScoreMatrix, home string, away string) {
+	fmt.Printf("%s win %.1f%%, %s win %.1f%%, regulation %.1f%%, overtime %.1f%%, shootout %.1f%%\n",
+		home, 100*matrix.HomeWinProbability, away, 100*(1-matrix.HomeWinProbability),
+		100*matrix.Regulation.Total(), 100*matrix.Overtime.Total(), 100*matrix.Shootout.Total())
+
+	shown := matrix.shownGoals()
+	fmt.Printf("%-9s", home+"\\"+away)
+	for awayScore := 0; awayScore <= shown; awayScore++ {
+		fmt.Printf(" %5d", awayScore)
+	}
+	fmt.Print("\n")
+	for homeScore := 0; homeScore <= shown; homeScore++ {
+		fmt.Printf("%-9d", homeScore)
+		for awayScore := 0; awayScore <= shown; awayScore++ {
+			if homeScore == awayScore {
+				fmt.Printf(" %5s", "-")
+				continue
+			}
+			fmt.Printf(" %4.1f%%", 100*matrix.Probability(homeScore, awayScore))
+		}
+		fmt.Print("\n")
+	}
+
+	fmt.Print("most likely scores:\n")
+	scores := []Score{}
+	for homeScore := 0; homeScore <= shown; homeScore++ {
+		for awayScore := 0; awayScore <= shown; awayScore++ {
+			if matrix.Probability(homeScore, awayScore) >= minShownScoreProbability {
+				scores = append(scores, Score{Home: homeScore, Away: awayScore})
+			}
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return matrix.Probability(scores[i].Home, scores[i].Away) > matrix.Probability(scores[j].Home, scores[j].Away)
+	})
+	for i, score := range scores {
+		if i == 10 {
+			break
+		}
+		fmt.Printf("  %s %d-%d %s: %.1f%% (regulation %.1f%%, overtime %.1f%%, shootout %.1f%%)\n",
+			home, score.Home, score.Away, away, 100*matrix.Probability(score.Home, score.Away),
+			100*matrix.Regulation[score.Home][score.Away], 100*matrix.Overtime[score.Home][score.Away], 100*matrix.Shootout[score.Home][score.Away])
+	}
+}
+
+// PrintScoreFrequencies compares the expected and actual number of games
+// ending each way and with each score, most expected first.
+func PrintScoreFrequencies(expected ScoreMatrix, actual ScoreMatrix, games int) {
+	fmt.Printf("%d finished games, expected vs. actual:\n", games)
+	for _, ending := range []struct {
+		name     string
+		expected ScoreGrid
+		actual   ScoreGrid
+	}{
+		{"regulation", expected.Regulation, actual.Regulation},
+		{"overtime", expected.Overtime, actual.Overtime},
+		{"shootout", expected.Shootout, actual.Shootout},
+	} {
+		fmt.Printf("  %-10s %7.1f %5.0f\n", ending.name, ending.expected.Total(), ending.actual.Total())
+	}
+
+	scores := []Score{}
+	for homeScore := range expected.Regulation {
+		for awayScore := range expected.Regulation[homeScore] {
+			if expected.Probability(homeScore, awayScore) >= minShownScoreProbability*float64(games) || actual.Probability(homeScore, awayScore) > 0 {
+				scores = append(scores, Score{Home: homeScore, Away: awayScore})
+			}
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return expected.Probability(scores[i].Home, scores[i].Away) > expected.Probability(scores[j].Home, scores[j].Away)
+	})
+	fmt.Printf("  %-10s %7s %5s\n", "home-away", "exp", "act")
+	for _, score := range scores {
+		fmt.Printf("  %-10s %7.1f %5.0f\n", fmt.Sprintf("%d-%d", score.Home, score.Away),
+			expected.Probability(score.Home, score.Away), actual.Probability(score.Home, score.Away))
+	}
+}
+
+func RunScores(home string, away string, compareActual bool) error {
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	model := inputs.EloModel()
+	if compareActual {
+		expected, actual, games := model.ScoreFrequencies(inputs.Season, &inputs.Teams)
+		PrintScoreFrequencies(expected, actual, games)
+		return nil
+	}
+
+	teamAbbrs := []string{}
+	for _, team := range []string{home, away} {
+		teamAbbr := ""
+		for abbr := range inputs.Teams {
+			if strings.EqualFold(abbr, team) {
+				teamAbbr = abbr
+			}
+		}
+		if teamAbbr == "" {
+			return fmt.Errorf("unknown team %s", team)
+		}
+		teamAbbrs = append(teamAbbrs, teamAbbr)
+	}
+	home, away = teamAbbrs[0], teamAbbrs[1]
+
+	fmt.Printf("%s (elo %.0f) hosting %s (elo %.0f)\n", home, inputs.Elos[home], away, inputs.Elos[away])
+	PrintScoreMatrix(model.MatchupScoreMatrix(home, away, inputs.Elos, &inputs.Teams), home, away)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewScoreMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		eloDiff float64
+	}{
+		{"even", 0},
+		{"home favored", 150},
+		{"away favored", -150},
+		{"lopsided", 400},
+	}
+	for _, test := range tests {
+		homeWinPct := WinProbability(test.eloDiff)
+		otChance := OvertimeProbability(test.eloDiff)
+		matrix := NewScoreMatrix(test.eloDiff, homeWinPct, false)
+
+		total := matrix.Regulation.Total() + matrix.Overtime.Total() + matrix.Shootout.Total()
+		if math.Abs(total-1) > testTolerance {
+			t.Errorf("%s: matrix sums to %f", test.name, total)
+		}
+		if math.Abs(matrix.Regulation.Total()-(1-otChance)) > testTolerance {
+			t.Errorf("%s: regulation %f, want %f", test.name, matrix.Regulation.Total(), 1-otChance)
+		}
+		if math.Abs(matrix.Overtime.Total()-otChance/2) > testTolerance || math.Abs(matrix.Shootout.Total()-otChance/2) > testTolerance {
+			t.Errorf("%s: overtime %f and shootout %f, want %f each", test.name, matrix.Overtime.Total(), matrix.Shootout.Total(), otChance/2)
+		}
+
+		homeWins := 0.0
+		for home := range matrix.Regulation {
+			for away := range matrix.Regulation[home] {
+				if home == away && matrix.Probability(home, away) != 0 {
+					t.Errorf("%s: tie %d-%d has chance %f", test.name, home, away, matrix.Probability(home, away))
+				}
+				margin := home - away
+				if margin != 1 && margin != -1 && (matrix.Overtime[home][away] != 0 || matrix.Shootout[home][away] != 0) {
+					t.Errorf("%s: %d-%d can't end past regulation", test.name, home, away)
+				}
+				if home > away {
+					homeWins += matrix.Probability(home, away)
+				}
+			}
+		}
+		if math.Abs(homeWins-homeWinPct) > testTolerance {
+			t.Errorf("%s: home wins %f, want %f", test.name, homeWins, homeWinPct)
+		}
+	}
+}
+
+func TestNewScoreMatrixPlayoff(t *testing.T) {
+	for _, eloDiff := range []float64{0, 150, -150} {
+		homeWinPct := WinProbability(eloDiff)
+		otChance := OvertimeProbability(eloDiff)
+		matrix := NewScoreMatrix(eloDiff, homeWinPct, true)
+		regular := NewScoreMatrix(eloDiff, homeWinPct, false)
+
+		if total := matrix.Shootout.Total(); total != 0 {
+			t.Errorf("elo diff %f: playoff shootout chance %f, want 0", eloDiff, total)
+		}
+		if math.Abs(matrix.Overtime.Total()-otChance) > testTolerance {
+			t.Errorf("elo diff %f: playoff overtime %f, want %f", eloDiff, matrix.Overtime.Total(), otChance)
+		}
+		// every final score is as likely as in the regular season, overtime
+		// just never ends in a shootout
+		for home := range matrix.Regulation {
+			for away := range matrix.Regulation[home] {
+				if math.Abs(matrix.Probability(home, away)-regular.Probability(home, away)) > testTolerance {
+					t.Errorf("elo diff %f: %d-%d playoff chance %f, regular season %f", eloDiff, home, away, matrix.Probability(home, away), regular.Probability(home, away))
+				}
+			}
+		}
+	}
+}
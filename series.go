@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// winsToTakeSeries is how many wins a best-of-seven takes
+const winsToTakeSeries = 4
+
+// SeriesHomeIce says which games of a best-of-seven the team with home ice
+// hosts, in the 2-2-1-1-1 format.
+var SeriesHomeIce = [2*winsToTakeSeries - 1]bool{true, true, false, false, true, false, true}
+
+// SeriesProbabilities are the chances of each way a series can end. HigherIn
+// and LowerIn are indexed by the number of games the series lasted, for the
+// team with home ice and its opponent.
+type SeriesProbabilities struct {
+	HigherWins float64
+	HigherIn   [2 * winsToTakeSeries]float64
+	LowerIn    [2 * winsToTakeSeries]float64
+}
+
+// LowerWins is the chance the team without home ice wins.
+func (p SeriesProbabilities) LowerWins() float64 {
+	return 1 - p.HigherWins
+}
+
+// Length is the chance the series lasts the given number of games.
+func (p SeriesProbabilities) Length(games int) float64 {
+	return p.HigherIn[games] + p.LowerIn[games]
+}
+
+// SeriesProbabilities works out exactly how a best-of-seven between the team
+// with home ice and its opponent ends, from the current number of wins each,
+// with the elos held where they are and the model's playoff adjustments.
+// Every game is won by somebody, so only the chance of each side winning a
+// game matters, overtime or not.
+func (m EloModel) SeriesProbabilities(higherElo float64, lowerElo float64, higherWins int, lowerWins int) SeriesProbabilities {
+	hostingWinPct := WinProbability(m.PlayoffEloDiff(higherElo, lowerElo))
+	visitingWinPct := 1 - WinProbability(m.PlayoffEloDiff(lowerElo, higherElo))
+
+	var probabilities SeriesProbabilities
+	var play func(higherWins int, lowerWins int, probability float64)
+	play = func(higherWins int, lowerWins int, probability float64) {
+		games := higherWins + lowerWins
+		if higherWins == winsToTakeSeries {
+			probabilities.HigherWins += probability
+			probabilities.HigherIn[games] += probability
+			return
+		}
+		if lowerWins == winsToTakeSeries {
+			probabilities.LowerIn[games] += probability
+			return
+		}
+		winPct := visitingWinPct
+		if SeriesHomeIce[games] {
+			winPct = hostingWinPct
+		}
+		play(higherWins+1, lowerWins, probability*winPct)
+		play(higherWins, lowerWins+1, probability*(1-winPct))
+	}
+	play(higherWins, lowerWins, 1)
+	return probabilities
+}
+
+// ParseSeriesState parses a series state like "3-1", with the team with home
+// ice's wins first.
+func ParseSeriesState(state string) (int, int, error) {
+	if state == "" {
+		return 0, 0, nil
+	}
+	higher, lower, ok := strings.Cut(state, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid series state %s, expected wins like 3-1", state)
+	}
+	higherWins, err := strconv.Atoi(higher)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid series state %s: %w", state, err)
+	}
+	lowerWins, err := strconv.Atoi(lower)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid series state %s: %w", state, err)
+	}
+	if higherWins < 0 || lowerWins < 0 || higherWins > winsToTakeSeries || lowerWins > winsToTakeSeries || (higherWins == winsToTakeSeries && lowerWins == winsToTakeSeries) {
+		return 0, 0, fmt.Errorf("invalid series state %s", state)
+	}
+	return higherWins, lowerWins, nil
+}
+
+func RunSeries(higher string, lower string, state string, model EloModel) error {
+	higherWins, lowerWins, err := ParseSeriesState(state)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+
+	teamAbbrs := []string{}
+	for _, team := range []string{higher, lower} {
+		teamAbbr := ""
+		for abbr := range inputs.Teams {
+			if strings.EqualFold(abbr, team) {
+				teamAbbr = abbr
+			}
+		}
+		if teamAbbr == "" {
+			return fmt.Errorf("unknown team %s", team)
+		}
+		teamAbbrs = append(teamAbbrs, teamAbbr)
+	}
+	higher, lower = teamAbbrs[0], teamAbbrs[1]
+
+	probabilities := model.SeriesProbabilities(inputs.Elos[higher], inputs.Elos[lower], higherWins, lowerWins)
+
+	fmt.Printf("%s (home ice, elo %.0f) vs. %s (elo %.0f), series %d-%d\n", higher, inputs.Elos[higher], lower, inputs.Elos[lower], higherWins, lowerWins)
+	for _, side := range []struct {
+		team string
+		wins float64
+		in   [2 * winsToTakeSeries]float64
+	}{
+		{higher, probabilities.HigherWins, probabilities.HigherIn},
+		{lower, probabilities.LowerWins(), probabilities.LowerIn},
+	} {
+		fmt.Printf("%s wins: %.1f%%", side.team, 100*side.wins)
+		for games := winsToTakeSeries; games < len(side.in); games++ {
+			if side.in[games] > 0 {
+				fmt.Printf(" (in %d: %.1f%%)", games, 100*side.in[games])
+			}
+		}
+		fmt.Print("\n")
+	}
+	fmt.Print("length:")
+	for games := winsToTakeSeries; games < 2*winsToTakeSeries; games++ {
+		if length := probabilities.Length(games); length > 0 {
+			fmt.Printf(" %d games %.1f%%", games, 100*length)
+		}
+	}
+	fmt.Print("\n")
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeriesProbabilities(t *testing.T) {
+	// no home ice and equal elos make every game a coin flip
+	evenModel := EloModel{PlayoffHomeIce: 0, PlayoffMultiplier: 1}
+
+	tests := []struct {
+		name       string
+		higherWins int
+		lowerWins  int
+		want       float64
+		wantLength [2 * winsToTakeSeries]float64
+	}{
+		{"start", 0, 0, 0.5, [2 * winsToTakeSeries]float64{4: 0.125, 5: 0.25, 6: 0.3125, 7: 0.3125}},
+		{"up 3-0", 3, 0, 0.9375, [2 * winsToTakeSeries]float64{4: 0.5, 5: 0.25, 6: 0.125, 7: 0.125}},
+		{"down 0-3", 0, 3, 0.0625, [2 * winsToTakeSeries]float64{4: 0.5, 5: 0.25, 6: 0.125, 7: 0.125}},
+		{"game 7", 3, 3, 0.5, [2 * winsToTakeSeries]float64{7: 1}},
+		{"won", 4, 2, 1, [2 * winsToTakeSeries]float64{6: 1}},
+		{"lost", 1, 4, 0, [2 * winsToTakeSeries]float64{5: 1}},
+	}
+	for _, test := range tests {
+		probabilities := evenModel.SeriesProbabilities(1500, 1500, test.higherWins, test.lowerWins)
+		if math.Abs(probabilities.HigherWins-test.want) > testTolerance {
+			t.Errorf("%s: higher wins %f, want %f", test.name, probabilities.HigherWins, test.want)
+		}
+		if math.Abs(probabilities.HigherWins+probabilities.LowerWins()-1) > testTolerance {
+			t.Errorf("%s: outcomes sum to %f", test.name, probabilities.HigherWins+probabilities.LowerWins())
+		}
+		for games, want := range test.wantLength {
+			if math.Abs(probabilities.Length(games)-want) > testTolerance {
+				t.Errorf("%s: length %d %f, want %f", test.name, games, probabilities.Length(games), want)
+			}
+		}
+	}
+}
+
+func TestSeriesProbabilitiesHomeIce(t *testing.T) {
+	model := EloModel{PlayoffHomeIce: 50, PlayoffMultiplier: 1.25}
+	withHomeIce := model.SeriesProbabilities(1500, 1500, 0, 0).HigherWins
+	if withHomeIce <= 0.5 {
+		t.Errorf("evenly matched team with home ice wins %f, want more than 0.5", withHomeIce)
+	}
+	stronger := model.SeriesProbabilities(1600, 1500, 0, 0).HigherWins
+	if stronger <= withHomeIce {
+		t.Errorf("stronger team with home ice wins %f, want more than %f", stronger, withHomeIce)
+	}
+}
+
+func TestParseSeriesState(t *testing.T) {
+	tests := []struct {
+		state      string
+		higherWins int
+		lowerWins  int
+		wantErr    bool
+	}{
+		{"", 0, 0, false},
+		{"0-0", 0, 0, false},
+		{"3-1", 3, 1, false},
+		{"2-3", 2, 3, false},
+		{"4-3", 4, 3, false},
+		{"4-4", 0, 0, true},
+		{"5-1", 0, 0, true},
+		{"-1-2", 0, 0, true},
+		{"3", 0, 0, true},
+		{"a-1", 0, 0, true},
+		{"1-b", 0, 0, true},
+	}
+	for _, test := range tests {
+		higherWins, lowerWins, err := ParseSeriesState(test.state)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseSeriesState(%q) error %v, want error %t", test.state, err, test.wantErr)
+			continue
+		}
+		if higherWins != test.higherWins || lowerWins != test.lowerWins {
+			t.Errorf("ParseSeriesState(%q) = %d-%d, want %d-%d", test.state, higherWins, lowerWins, test.higherWins, test.lowerWins)
+		}
+	}
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultServerRuns = 10000
+
+// finished simulation jobs beyond this many are forgotten, oldest first
+const maxFinishedJobs = 100
+
+// new simulation jobs are turned away while this many are queued or running
+const maxPendingJobs = 10
+
+type TeamOddsJSON struct {
+	Team             string  `json:"team"`
+	Runs             int     `json:"runs"`
+	Playoffs         float64 `json:"playoffs"`
+	D1Seed           float64 `json:"d1"`
+	D2Seed           float64 `json:"d2"`
+	D3Seed           float64 `json:"d3"`
+	WC1              float64 `json:"wc1"`
+	WC2              float64 `json:"wc2"`
+	PresidentsTrophy float64 `json:"presidents"`
+	ConferenceLeader float64 `json:"conference_leader"`
+	LastOverall      float64 `json:"last_overall"`
+}
+
+type StandingsTeamJSON struct {
+	Team           string `json:"team"`
+	Name           string `json:"name"`
+	Wins           int    `json:"wins"`
+	Losses         int    `json:"losses"`
+	OTLosses       int    `json:"ot_losses"`
+	Points         int    `json:"points"`
+	RegulationWins int    `json:"regulation_wins"`
+	GoalsFor       int    `json:"goals_for"`
+	GoalsAgainst   int    `json:"goals_against"`
+}
+
+type StandingsDivisionJSON struct {
+	Name  string              `json:"name"`
+	Teams []StandingsTeamJSON `json:"teams"`
+}
+
+type StandingsConferenceJSON struct {
+	Name      string                  `json:"name"`
+	Divisions []StandingsDivisionJSON `json:"divisions"`
+	WildCard  []StandingsTeamJSON     `json:"wild_card"`
+}
+
+type GameJSON struct {
+	GamePK      int64    `json:"game_pk"`
+	Date        string   `json:"date"`
+	Status      string   `json:"status"`
+	HomeTeam    string   `json:"home_team"`
+	AwayTeam    string   `json:"away_team"`
+	HomeScore   int      `json:"home_score"`
+	AwayScore   int      `json:"away_score"`
+	HomeWinProb *float64 `json:"home_win_prob,omitempty"`
+}
+
+type HistogramBucketJSON struct {
+	Value       int     `json:"value"`
+	Probability float64 `json:"probability"`
+}
+
+type DistributionJSON struct {
+	Stat string `json:"stat"`
+	Percentiles
+	Histogram []HistogramBucketJSON `json:"histogram"`
+}
+
+type TeamDistributionsJSON struct {
+	Team          string             `json:"team"`
+	Distributions []DistributionJSON `json:"distributions"`
+}
+
+type ScoreProbabilityJSON struct {
+	Home        int     `json:"home"`
+	Away        int     `json:"away"`
+	Probability float64 `json:"probability"`
+	Regulation  float64 `json:"regulation"`
+	Overtime    float64 `json:"overtime"`
+	Shootout    float64 `json:"shootout"`
+}
+
+type ScoreMatrixJSON struct {
+	HomeTeam            string                 `json:"home_team"`
+	AwayTeam            string                 `json:"away_team"`
+	HomeWinProbability  float64                `json:"home_win_probability"`
+	OvertimeProbability float64                `json:"overtime_probability"`
+	Scores              []ScoreProbabilityJSON `json:"scores"`
+}
+
+type SimulationJob struct {
+	ID       string         `json:"id"`
+	Status   string         `json:"status"`
+	Runs     int            `json:"runs"`
+	Seed     int64          `json:"seed"`
+	Started  string         `json:"started,omitempty"`
+	Finished string         `json:"finished,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Odds     []TeamOddsJSON `json:"odds,omitempty"`
+}
+
+// Server serves ratings, standings, predictions and simulation results over
+// HTTP, reloading its data whenever the files in the data directory change.
+type Server struct {
+	mu            sync.RWMutex
+	inputs        SimulationInputs
+	results       map[string]*TeamSimulationResults
+	resultsRun    OddsHistoryRow
+	distributions map[string]TeamDistributions
+	dataModTime   time.Time
+	jobs          map[string]*SimulationJob
+	nextJobID     int
+	simulationsMu sync.Mutex
+}
+
+func NewServer() (*Server, error) {
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		inputs: SimulationInputs{Teams: teams},
+		jobs:   make(map[string]*SimulationJob),
+	}
+	if err := server.Reload(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func (s *Server) dataFiles() []string {
+	return []string{"data/preseason_elo.csv", fmt.Sprintf("data/%s.csv", currentSeason), historyFile, distributionsFile}
+}
+
+// latestDataModTime returns the most recent modification time of the data
+// files the server is built from.
+func (s *Server) latestDataModTime() time.Time {
+	var latest time.Time
+	for _, file := range s.dataFiles() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (s *Server) Reload() error {
+	modTime := s.latestDataModTime()
+
+	preseasonElos, err := LoadPreseasonElos()
+	if err != nil {
+		return err
+	}
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+	// a missing history just means nothing has been simulated yet
+	results, run, err := LatestSimulationResults()
+	if err != nil {
+		fmt.Printf("no simulation results loaded: %s\n", err)
+	}
+	distributions, err := LoadDistributions()
+	if err != nil {
+		fmt.Printf("no distributions loaded: %s\n", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputs = SimulationInputs{
+		Elos:   CurrentElos(preseasonElos, season),
+		Season: season,
+		Teams:  s.inputs.Teams,
+	}
+	if results != nil {
+		s.results = results
+		s.resultsRun = run
+	}
+	if distributions != nil {
+		s.distributions = distributions
+	}
+	s.dataModTime = modTime
+	fmt.Printf("loaded %d games and %d elos\n", len(season), len(s.inputs.Elos))
+	return nil
+}
+
+// WatchData polls the data files and reloads when any of them change, such as
+// after update-season writes a new season file.
+func (s *Server) WatchData(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.mu.RLock()
+		loaded := s.dataModTime
+		s.mu.RUnlock()
+
+		if s.latestDataModTime().After(loaded) {
+			if err := s.Reload(); err != nil {
+				fmt.Printf("could not reload data: %s\n", err)
+			}
+		}
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/teams", s.handleTeams)
+	mux.HandleFunc("/ratings", s.handleRatings)
+	mux.HandleFunc("/standings", s.handleStandings)
+	mux.HandleFunc("/games", s.handleGames)
+	mux.HandleFunc("/odds", s.handleOdds)
+	mux.HandleFunc("/odds/", s.handleOdds)
+	mux.HandleFunc("/distributions", s.handleDistributions)
+	mux.HandleFunc("/distributions/", s.handleDistributions)
+	mux.HandleFunc("/scores", s.handleScores)
+	mux.HandleFunc("/simulate", s.handleSimulate)
+	mux.HandleFunc("/simulate/", s.handleSimulationJob)
+	return mux
+}
+
+func RunServer(addr string, reloadInterval time.Duration) error {
+	server, err := NewServer()
+	if err != nil {
+		return err
+	}
+	go server.WatchData(reloadInterval)
+
+	fmt.Printf("listening on %s\n", addr)
+	return http.ListenAndServe(addr, server.Handler())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Printf("could not write response: %s\n", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *Server) handleTeams(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	teams := []NHLTeamJSON{}
+	for _, team := range s.inputs.Teams {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].Abbreviation < teams[j].Abbreviation
+	})
+	writeJSON(w, http.StatusOK, teams)
+}
+
+func (s *Server) handleRatings(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, s.inputs.Elos)
+}
+
+func standingsTeamJSON(team *ReportTeam) StandingsTeamJSON {
+	return StandingsTeamJSON{
+		Team:           team.Abbr,
+		Name:           team.Name,
+		Wins:           team.Stats.Wins,
+		Losses:         team.Stats.RegulationLosses(),
+		OTLosses:       team.Stats.OTLosses,
+		Points:         team.Stats.Points,
+		RegulationWins: team.Stats.RegulationWins,
+		GoalsFor:       team.Stats.GoalsFor,
+		GoalsAgainst:   team.Stats.GoalsAgainst,
+	}
+}
+
+func (s *Server) handleStandings(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	report := BuildReport(s.inputs.Elos, s.inputs.Season, s.inputs.Teams, s.results, s.resultsRun, SimulationOutputs{Distributions: s.distributions})
+	s.mu.RUnlock()
+
+	conferences := []StandingsConferenceJSON{}
+	for _, conference := range report.Conferences {
+		conferenceJSON := StandingsConferenceJSON{Name: conference.Name, WildCard: []StandingsTeamJSON{}}
+		for _, division := range conference.Divisions {
+			divisionJSON := StandingsDivisionJSON{Name: division.Name}
+			for _, team := range division.Teams {
+				divisionJSON.Teams = append(divisionJSON.Teams, standingsTeamJSON(team))
+			}
+			conferenceJSON.Divisions = append(conferenceJSON.Divisions, divisionJSON)
+		}
+		for _, team := range conference.WildCard {
+			conferenceJSON.WildCard = append(conferenceJSON.WildCard, standingsTeamJSON(team))
+		}
+		conferences = append(conferences, conferenceJSON)
+	}
+	writeJSON(w, http.StatusOK, conferences)
+}
+
+func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		writeJSONError(w, http.StatusBadRequest, "date is required")
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	games := []GameJSON{}
+	for _, game := range s.inputs.Season {
+		if game.Date != date {
+			continue
+		}
+		gameJSON := GameJSON{
+			GamePK:    game.GamePK,
+			Date:      game.Date,
+			Status:    game.Status,
+			HomeTeam:  game.HomeTeam,
+			AwayTeam:  game.AwayTeam,
+			HomeScore: game.HomeScore,
+			AwayScore: game.AwayScore,
+		}
+		if game.Status != "Final" {
+			_, homeWinPct := GameWinProbability(game, s.inputs.Elos, &s.inputs.Teams)
+			gameJSON.HomeWinProb = &homeWinPct
+		}
+		games = append(games, gameJSON)
+	}
+	writeJSON(w, http.StatusOK, games)
+}
+
+func oddsJSON(simulationResults map[string]*TeamSimulationResults, runs int) []TeamOddsJSON {
+	odds := []TeamOddsJSON{}
+	for team, results := range simulationResults {
+		odds = append(odds, TeamOddsJSON{
+			Team:             team,
+			Runs:             runs,
+			Playoffs:         results.MadePlayoffs / float64(runs),
+			D1Seed:           results.D1Seed / float64(runs),
+			D2Seed:           results.D2Seed / float64(runs),
+			D3Seed:           results.D3Seed / float64(runs),
+			WC1:              results.WC1 / float64(runs),
+			WC2:              results.WC2 / float64(runs),
+			PresidentsTrophy: results.PresidentsTrophy / float64(runs),
+			ConferenceLeader: results.ConferenceLeader / float64(runs),
+			LastOverall:      results.LastOverall / float64(runs),
+		})
+	}
+	sort.Slice(odds, func(i, j int) bool {
+		return odds[i].Team < odds[j].Team
+	})
+	return odds
+}
+
+func (s *Server) handleOdds(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.results == nil {
+		writeJSONError(w, http.StatusNotFound, "no simulation results available")
+		return
+	}
+
+	team := strings.TrimPrefix(r.URL.Path, "/odds")
+	team = strings.Trim(team, "/")
+	if team == "" {
+		writeJSON(w, http.StatusOK, oddsJSON(s.results, s.resultsRun.Runs))
+		return
+	}
+
+	for _, odds := range oddsJSON(s.results, s.resultsRun.Runs) {
+		if strings.EqualFold(odds.Team, team) {
+			writeJSON(w, http.StatusOK, odds)
+			return
+		}
+	}
+	writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown team %s", team))
+}
+
+func distributionsJSON(team string, teamDistributions TeamDistributions) TeamDistributionsJSON {
+	teamJSON := TeamDistributionsJSON{Team: team, Distributions: []DistributionJSON{}}
+	for _, stat := range distributionStats {
+		histogram, ok := teamDistributions[stat]
+		if !ok {
+			continue
+		}
+		distribution := DistributionJSON{Stat: stat, Percentiles: histogram.Percentiles(), Histogram: []HistogramBucketJSON{}}
+		for _, value := range histogram.Values() {
+			distribution.Histogram = append(distribution.Histogram, HistogramBucketJSON{Value: value, Probability: histogram[value]})
+		}
+		teamJSON.Distributions = append(teamJSON.Distributions, distribution)
+	}
+	return teamJSON
+}
+
+func (s *Server) handleDistributions(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.distributions == nil {
+		writeJSONError(w, http.StatusNotFound, "no distributions available")
+		return
+	}
+
+	team := strings.Trim(strings.TrimPrefix(r.URL.Path, "/distributions"), "/")
+	if team == "" {
+		teams := []TeamDistributionsJSON{}
+		for abbr, teamDistributions := range s.distributions {
+			teams = append(teams, distributionsJSON(abbr, teamDistributions))
+		}
+		sort.Slice(teams, func(i, j int) bool {
+			return teams[i].Team < teams[j].Team
+		})
+		writeJSON(w, http.StatusOK, teams)
+		return
+	}
+
+	for abbr, teamDistributions := range s.distributions {
+		if strings.EqualFold(abbr, team) {
+			writeJSON(w, http.StatusOK, distributionsJSON(abbr, teamDistributions))
+			return
+		}
+	}
+	writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown team %s", team))
+}
+
+// handleScores returns the chance of every final score of the home team
+// hosting the away team, however the game ends.
+func (s *Server) handleScores(w http.ResponseWriter, r *http.Request) {
+	home := r.URL.Query().Get("home")
+	away := r.URL.Query().Get("away")
+	if home == "" || away == "" {
+		writeJSONError(w, http.StatusBadRequest, "home and away are required")
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	teamAbbrs := []string{}
+	for _, team := range []string{home, away} {
+		teamAbbr := ""
+		for abbr := range s.inputs.Teams {
+			if strings.EqualFold(abbr, team) {
+				teamAbbr = abbr
+			}
+		}
+		if teamAbbr == "" {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown team %s", team))
+			return
+		}
+		teamAbbrs = append(teamAbbrs, teamAbbr)
+	}
+
+	matrix := s.inputs.EloModel().MatchupScoreMatrix(teamAbbrs[0], teamAbbrs[1], s.inputs.Elos, &s.inputs.Teams)
+	matrixJSON := ScoreMatrixJSON{
+		HomeTeam:            teamAbbrs[0],
+		AwayTeam:            teamAbbrs[1],
+		HomeWinProbability:  matrix.HomeWinProbability,
+		OvertimeProbability: matrix.OvertimeProbability,
+		Scores:              []ScoreProbabilityJSON{},
+	}
+	for homeScore := range matrix.Regulation {
+		for awayScore := range matrix.Regulation[homeScore] {
+			if homeScore == awayScore {
+				continue
+			}
+			matrixJSON.Scores = append(matrixJSON.Scores, ScoreProbabilityJSON{
+				Home:        homeScore,
+				Away:        awayScore,
+				Probability: matrix.Probability(homeScore, awayScore),
+				Regulation:  matrix.Regulation[homeScore][awayScore],
+				Overtime:    matrix.Overtime[homeScore][awayScore],
+				Shootout:    matrix.Shootout[homeScore][awayScore],
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, matrixJSON)
+}
+
+// handleSimulate starts a new simulation run in the background and returns the
+// ID of the job, which can be polled at /simulate/{id}. It returns 429 when
+// maxPendingJobs are already waiting.
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "simulate requires POST")
+		return
+	}
+
+	job := &SimulationJob{
+		Status: "queued",
+		Runs:   defaultServerRuns,
+		Seed:   time.Now().Unix(),
+	}
+	if runs := r.FormValue("runs"); runs != "" {
+		parsed, err := strconv.Atoi(runs)
+		if err != nil || parsed <= 0 || parsed > numRuns {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid runs %q, expected 1 to %d", runs, numRuns))
+			return
+		}
+		job.Runs = parsed
+	}
+	if seed := r.FormValue("seed"); seed != "" {
+		parsed, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid seed %q", seed))
+			return
+		}
+		job.Seed = parsed
+	}
+
+	s.mu.Lock()
+	if s.pendingJobs() >= maxPendingJobs {
+		s.mu.Unlock()
+		writeJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("%d simulations are already queued or running, try again later", maxPendingJobs))
+		return
+	}
+	s.nextJobID += 1
+	job.ID = strconv.Itoa(s.nextJobID)
+	s.jobs[job.ID] = job
+	s.evictFinishedJobs()
+	inputs := s.inputs
+	s.mu.Unlock()
+
+	go s.runSimulationJob(job, inputs)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": job.ID})
+}
+
+// pendingJobs counts the queued and running jobs. It must be called with s.mu
+// held.
+func (s *Server) pendingJobs() int {
+	pending := 0
+	for _, job := range s.jobs {
+		if job.Status == "queued" || job.Status == "running" {
+			pending += 1
+		}
+	}
+	return pending
+}
+
+// evictFinishedJobs forgets the oldest finished jobs once there are more than
+// maxFinishedJobs of them. Queued and running jobs are always kept. It must be
+// called with s.mu held.
+func (s *Server) evictFinishedJobs() {
+	finished := []int{}
+	for id, job := range s.jobs {
+		if job.Status == "done" || job.Status == "failed" {
+			n, _ := strconv.Atoi(id)
+			finished = append(finished, n)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Ints(finished)
+	for _, n := range finished[:len(finished)-maxFinishedJobs] {
+		delete(s.jobs, strconv.Itoa(n))
+	}
+}
+
+func (s *Server) runSimulationJob(job *SimulationJob, inputs SimulationInputs) {
+	// each job draws from its own random streams, so its results are
+	// reproducible from its seed; jobs still run one at a time so they don't
+	// compete for the CPU
+	s.simulationsMu.Lock()
+	defer s.simulationsMu.Unlock()
+
+	s.mu.Lock()
+	job.Status = "running"
+	job.Started = HistoryTimestamp(time.Now())
+	s.mu.Unlock()
+
+	inputs.Streams = &RandomStreams{Seed: uint64(job.Seed)}
+	results := SimulatePlayoffOdds(inputs, job.Runs)
+	finished := time.Now()
+	err := AppendOddsHistory(finished, job.Seed, job.Runs, inputs.Season, results)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.evictFinishedJobs()
+	job.Finished = HistoryTimestamp(finished)
+	job.Odds = oddsJSON(results, job.Runs)
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+		return
+	}
+	job.Status = "done"
+	s.results = results
+	lastGame, _ := LastFinalGame(inputs.Season)
+	s.resultsRun = OddsHistoryRow{
+		Timestamp:     job.Finished,
+		Seed:          job.Seed,
+		Runs:          job.Runs,
+		LastFinalGame: lastGame.GamePK,
+		LastFinalDate: lastGame.Date,
+	}
+}
+
+func (s *Server) handleSimulationJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/simulate"), "/")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown simulation job %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestHandleSimulateQueueFull(t *testing.T) {
+	server := &Server{jobs: make(map[string]*SimulationJob)}
+	for i := 1; i <= maxPendingJobs; i++ {
+		status := "queued"
+		if i == 1 {
+			status = "running"
+		}
+		server.jobs[strconv.Itoa(i)] = &SimulationJob{ID: strconv.Itoa(i), Status: status}
+	}
+	server.nextJobID = maxPendingJobs
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/simulate", nil))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status %d with a full queue, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	if len(server.jobs) != maxPendingJobs {
+		t.Errorf("%d jobs after turning one away, want %d", len(server.jobs), maxPendingJobs)
+	}
+
+	// finished jobs don't hold up new ones, but don't start one here since it
+	// would simulate for real
+	server.jobs["1"].Status = "done"
+	if got := server.pendingJobs(); got != maxPendingJobs-1 {
+		t.Errorf("%d pending jobs, want %d", got, maxPendingJobs-1)
+	}
+}
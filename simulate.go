@@ -4,161 +4,430 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
 	"sort"
 	"time"
 
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
+// TeamSimulationResults tallies how often a team finished in each playoff
+// spot, and first or last overall. D1Seed is the division title. Monte Carlo
+// runs each count once; exactly enumerated seasons count with their
+// probability.
 type TeamSimulationResults struct {
-	MadePlayoffs int
-	D1Seed       int
-	D2Seed       int
-	D3Seed       int
-	WC1          int
-	WC2          int
+	MadePlayoffs     float64
+	D1Seed           float64
+	D2Seed           float64
+	D3Seed           float64
+	WC1              float64
+	WC2              float64
+	PresidentsTrophy float64
+	ConferenceLeader float64
+	LastOverall      float64
 }
 
 const numRuns = 1000000
 
-func RunSimulation() error {
-	// 1665171464 generates 3-way tie
-	seed := time.Now().Unix()
-	//seed := int64(1665171464)
-	rand.Seed(seed)
-	fmt.Printf("using seed %d\n", seed)
+// most goals a team is considered to score when looking for likely scores
+const maxLikelyGoals = 12
+
+// SimulationInputs holds everything a batch of season simulations needs: the
+// current elo for every team, the season schedule and the team metadata.
+// Playoffs are the playoff games so far, which season simulations leave alone.
+// Model is the elo model to simulate with, the default one when nil, and
+// Streams the per-game random streams to draw from, the global random sources
+// when nil.
+type SimulationInputs struct {
+	Elos     map[string]float64
+	Season   []NHLGameCSVRow
+	Playoffs []NHLGameCSVRow
+	Teams    map[string]NHLTeamJSON
+	Scenario *Scenario
+	Model    *EloModel
+	Streams  *RandomStreams
+}
+
+func (inputs SimulationInputs) EloModel() EloModel {
+	if inputs.Model == nil {
+		return DefaultEloModel
+	}
+	return *inputs.Model
+}
+
+// LoadSimulationInputs loads the elos, games and teams, reporting what it
+// loaded on stderr so commands can keep stdout for their output.
+func LoadSimulationInputs() (SimulationInputs, error) {
 	elos, err := LoadPreseasonElos()
 	if err != nil {
-		return err
+		return SimulationInputs{}, err
 	}
-	fmt.Printf("loaded %d elos\n", len(elos))
+	fmt.Fprintf(os.Stderr, "loaded %d elos\n", len(elos))
 
-	season, err := LoadNHLSeason()
+	season, playoffs, err := LoadNHLGames()
 	if err != nil {
-		return err
+		return SimulationInputs{}, err
+	}
+	fmt.Fprintf(os.Stderr, "loaded %d games\n", len(season))
+	if len(playoffs) > 0 {
+		fmt.Fprintf(os.Stderr, "loaded %d playoff games\n", len(playoffs))
 	}
-	fmt.Printf("loaded %d games\n", len(season))
 
 	teams, err := GetNHLTeams()
 	if err != nil {
-		return err
+		return SimulationInputs{}, err
 	}
-	fmt.Printf("loaded %d teams\n", len(teams))
+	fmt.Fprintf(os.Stderr, "loaded %d teams\n", len(teams))
+
+	return SimulationInputs{
+		Elos:     CurrentElos(CurrentElos(elos, season), playoffs),
+		Season:   season,
+		Playoffs: playoffs,
+		Teams:    teams,
+	}, nil
+}
 
-	// one pass to grab any updated elos
+// CurrentElos returns a copy of the preseason elos updated with the post-game
+// elo of every game in the season that has one.
+func CurrentElos(preseasonElos map[string]float64, season []NHLGameCSVRow) map[string]float64 {
+	elos := make(map[string]float64)
+	for team, elo := range preseasonElos {
+		elos[team] = elo
+	}
 	for _, game := range season {
 		if game.AwayELOPost > 0 {
-			fmt.Printf("Updating ELO for %s from game %d\n", game.AwayTeam, game.GamePK)
 			elos[game.AwayTeam] = game.AwayELOPost
 		}
 		if game.HomeELOPost > 0 {
-			fmt.Printf("Updating ELO for %s from game %d\n", game.AwayTeam, game.GamePK)
 			elos[game.HomeTeam] = game.HomeELOPost
 		}
 	}
+	return elos
+}
+
+// SimulationOptions controls how simulate runs. With neither a precision nor
+// a time budget it does numRuns runs; otherwise it runs in batches until the
+// playoff odds of every team near the line are within Precision, or until
+// TimeBudget runs out. Once few enough outcome combinations are left, at most
+// ExactLimit, or when Exact is set, every combination is enumerated instead,
+// unless goal differential breaks a tie in any of them.
+type SimulationOptions struct {
+	ScenarioPath string
+	Exact        bool
+	ExactLimit   int
+	Precision    float64
+	TimeBudget   time.Duration
+	LotteryPath  string
+}
+
+// number of runs between precision and time budget checks
+const simulationBatchSize = 10000
+
+func RunSimulation(options SimulationOptions) error {
+	// 1665171464 generates 3-way tie
+	seed := time.Now().Unix()
+	//seed := int64(1665171464)
+	rand.Seed(seed)
+	fmt.Printf("using seed %d\n", seed)
 
+	inputs, err := LoadSimulationInputs()
+	if err != nil {
+		return err
+	}
+	if options.ScenarioPath != "" {
+		if options.Exact {
+			return fmt.Errorf("exact enumeration does not support scenarios")
+		}
+		inputs.Scenario, err = LoadScenario(options.ScenarioPath, inputs.Season)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("using scenario %s\n", inputs.Scenario.Name)
+	}
+
+	lotteryRules := DefaultLotteryRules()
+	if options.LotteryPath != "" {
+		lotteryRules, err = LoadLotteryRules(options.LotteryPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	combinations := ExactCombinations(inputs.Season)
+	exact := options.Exact || (inputs.Scenario == nil && combinations <= float64(options.ExactLimit))
+
+	start := time.Now()
+	var simulationResults map[string]*TeamSimulationResults
+	var importanceTally *GameImportanceTally
+	var distributions map[string]TeamDistributions
+	var cutLines ConferenceCutLines
+	var matchups Matchups
+	var lotteryOrders LotteryOrders
+	resetTallies := func() {
+		simulationResults = NewTeamSimulationResults(inputs.Teams)
+		importanceTally = NewGameImportanceTally(inputs.Season)
+		distributions = NewTeamDistributions(inputs.Teams)
+		cutLines = NewConferenceCutLines(inputs.Teams)
+		matchups = make(Matchups)
+		lotteryOrders = make(LotteryOrders)
+	}
+	resetTallies()
+	observe := func(simulatedSeason []NHLGameCSVRow, simulatedStandings Standings) {
+		TallyStandings(simulationResults, simulatedStandings)
+		importanceTally.Observe(simulatedSeason, simulatedStandings)
+		TallyDistributions(distributions, simulatedStandings, 1)
+		TallyCutLines(cutLines, simulatedStandings, inputs.Teams, 1)
+		TallyMatchups(matchups, simulatedStandings, inputs.Teams, 1)
+		TallyLotteryOrders(lotteryOrders, simulatedStandings, 1)
+	}
+	runs := 0
+	if exact {
+		fmt.Printf("enumerating %.0f outcome combinations\n", combinations)
+		goalsDecide := false
+		EnumerateSeasons(inputs, func(simulatedSeason []NHLGameCSVRow, simulatedStandings Standings, weight float64) {
+			if goalsDecide || GoalsDecideStandings(simulatedStandings, simulatedSeason) {
+				goalsDecide = true
+				return
+			}
+			TallyWeightedStandings(simulationResults, simulatedStandings, weight)
+			importanceTally.ObserveWeighted(simulatedSeason, simulatedStandings, weight)
+			TallyDistributions(distributions, simulatedStandings, weight)
+			TallyCutLines(cutLines, simulatedStandings, inputs.Teams, weight)
+			TallyMatchups(matchups, simulatedStandings, inputs.Teams, weight)
+			TallyLotteryOrders(lotteryOrders, simulatedStandings, weight)
+		})
+		runs = 1
+		// enumerated seasons only have each result's most likely score, so when
+		// goals break a tie the odds would come from made-up margins
+		if goalsDecide {
+			fmt.Print("goal differential breaks a tie in some outcomes, so simulating instead\n")
+			resetTallies()
+			exact = false
+			runs = 0
+		}
+	}
+	switch {
+	case exact:
+		// every combination is already tallied
+	case options.Precision > 0 || options.TimeBudget > 0:
+		for {
+			RunSimulations(inputs, simulationBatchSize, observe)
+			runs += simulationBatchSize
+			widest := WidestPlayoffInterval(simulationResults, runs)
+			if options.Precision > 0 && widest <= options.Precision {
+				fmt.Printf("reached precision of %.3f%% after %d runs\n", 100*widest, runs)
+				break
+			}
+			if options.TimeBudget > 0 && time.Since(start) >= options.TimeBudget {
+				fmt.Printf("time budget ran out after %d runs with precision of %.3f%%\n", runs, 100*widest)
+				break
+			}
+		}
+	default:
+		RunSimulations(inputs, numRuns, observe)
+		runs = numRuns
+	}
+	draftPicks := lotteryOrders.DraftPicks(lotteryRules)
+	duration := time.Since(start)
+	fmt.Printf("execution took %s\n", duration)
+
+	marks := ClinchMarks(CalculateClinchStatus(inputs.Teams, inputs.Season))
+	PrintSimulationResults(simulationResults, runs, exact, inputs.Scenario, marks)
+	PrintDistributions(distributions)
+	PrintCutLines(cutLines)
+	PrintMatchups(matchups, inputs.Teams, float64(runs))
+	PrintDraftPicks(draftPicks, float64(runs))
+
+	// the history and game ratings only track the baseline, not what-if scenarios
+	if inputs.Scenario != nil {
+		return nil
+	}
+	ApplyGameRatings(inputs.Season, inputs.Elos, importanceTally.Importance())
+	if err := WriteGameRatings(inputs.Season); err != nil {
+		return err
+	}
+	if err := WriteDistributions(distributions); err != nil {
+		return err
+	}
+	if err := WriteCutLines(cutLines); err != nil {
+		return err
+	}
+	if err := WriteMatchups(matchups, float64(runs)); err != nil {
+		return err
+	}
+	if err := WriteDraftPicks(draftPicks, float64(runs)); err != nil {
+		return err
+	}
+	if exact {
+		ScaleSimulationResults(simulationResults, numRuns)
+		runs = numRuns
+	}
+	return AppendOddsHistory(time.Now(), seed, runs, inputs.Season, simulationResults)
+}
+
+// RunSimulations simulates the rest of the season runs times, handing each
+// simulated season and its standings to observe.
+func RunSimulations(inputs SimulationInputs, runs int, observe func(simulatedSeason []NHLGameCSVRow, standings Standings)) {
+	for i := 0; i < runs; i++ {
+		simulatedSeason := SimulateSeason(inputs, i)
+		simulatedStandings := CalculateStandings(&inputs.Teams, &simulatedSeason)
+		observe(simulatedSeason, simulatedStandings)
+	}
+}
+
+// SimulatePlayoffOdds simulates the rest of the season runs times and tallies
+// how often each team made the playoffs and in which seed.
+func SimulatePlayoffOdds(inputs SimulationInputs, runs int) map[string]*TeamSimulationResults {
+	simulationResults := NewTeamSimulationResults(inputs.Teams)
+	RunSimulations(inputs, runs, func(simulatedSeason []NHLGameCSVRow, simulatedStandings Standings) {
+		TallyStandings(simulationResults, simulatedStandings)
+	})
+
+	return simulationResults
+}
+
+func NewTeamSimulationResults(teams map[string]NHLTeamJSON) map[string]*TeamSimulationResults {
 	simulationResults := make(map[string]*TeamSimulationResults)
 	for _, team := range teams {
 		simulationResults[team.Abbreviation] = &TeamSimulationResults{}
 	}
+	return simulationResults
+}
 
-	start := time.Now()
+func TallyStandings(simulationResults map[string]*TeamSimulationResults, simulatedStandings Standings) {
+	TallyWeightedStandings(simulationResults, simulatedStandings, 1)
+}
 
-	for i := 0; i < numRuns; i++ {
-		simulatedSeason := SimulateSeason(&elos, &season, &teams)
-		simulatedStandings := CalculateStandings(&teams, &simulatedSeason)
-		for _, divisionStandings := range simulatedStandings.DivisionSeeds {
-			for i, team := range divisionStandings {
-				teamStandings := simulationResults[team]
-				teamStandings.MadePlayoffs += 1
-				if i == 0 {
-					teamStandings.D1Seed += 1
-				} else if i == 1 {
-					teamStandings.D2Seed += 1
-				} else if i == 2 {
-					teamStandings.D3Seed += 1
-				}
+func TallyWeightedStandings(simulationResults map[string]*TeamSimulationResults, simulatedStandings Standings, weight float64) {
+	for _, divisionStandings := range simulatedStandings.DivisionSeeds {
+		for i, team := range divisionStandings {
+			teamStandings := simulationResults[team]
+			teamStandings.MadePlayoffs += weight
+			if i == 0 {
+				teamStandings.D1Seed += weight
+			} else if i == 1 {
+				teamStandings.D2Seed += weight
+			} else if i == 2 {
+				teamStandings.D3Seed += weight
 			}
 		}
-		for _, conferenceWildCards := range simulatedStandings.WildCards {
-			for i, team := range conferenceWildCards {
-				teamStandings := simulationResults[team]
-				teamStandings.MadePlayoffs += 1
-				if i == 0 {
-					teamStandings.WC1 += 1
-				} else if i == 1 {
-					teamStandings.WC2 += 1
-				}
+	}
+	for _, conferenceWildCards := range simulatedStandings.WildCards {
+		for i, team := range conferenceWildCards {
+			teamStandings := simulationResults[team]
+			teamStandings.MadePlayoffs += weight
+			if i == 0 {
+				teamStandings.WC1 += weight
+			} else if i == 1 {
+				teamStandings.WC2 += weight
 			}
 		}
 	}
+	for team, ranks := range simulatedStandings.Ranks {
+		teamStandings := simulationResults[team]
+		if ranks.League == 1 {
+			teamStandings.PresidentsTrophy += weight
+		}
+		if ranks.Conference == 1 {
+			teamStandings.ConferenceLeader += weight
+		}
+		if ranks.League == len(simulatedStandings.League) {
+			teamStandings.LastOverall += weight
+		}
+	}
+}
 
-	duration := time.Since(start)
-	fmt.Printf("execution took %s\n", duration)
-
-	fmt.Print("results:\n")
+// PrintSimulationResults prints each team's odds with their 95% intervals,
+// prefixed with its clinch or elimination mark if it has one.
+func PrintSimulationResults(simulationResults map[string]*TeamSimulationResults, runs int, exact bool, scenario *Scenario, marks map[string]string) {
+	if scenario != nil {
+		fmt.Printf("results for scenario %s:\n", scenario.Name)
+	} else {
+		fmt.Print("results:\n")
+	}
+	format := func(successes float64) string {
+		return FormatProbability(successes, runs, exact)
+	}
 	for team, standings := range simulationResults {
-		playoffChance := float64(standings.MadePlayoffs) / float64(numRuns)
-		d1Chance := 100. * float64(standings.D1Seed) / float64(numRuns)
-		d2Chance := 100. * float64(standings.D2Seed) / float64(numRuns)
-		d3Chance := 100. * float64(standings.D3Seed) / float64(numRuns)
-		wc1Chance := 100. * float64(standings.WC1) / float64(numRuns)
-		wc2Chance := 100. * float64(standings.WC2) / float64(numRuns)
-		fmt.Printf("%s: %f%% playoffs (%f D1, %f D2, %f D3, %f WC1, %f WC2)\n", team, playoffChance, d1Chance, d2Chance, d3Chance, wc1Chance, wc2Chance)
+		label := team
+		if mark := marks[team]; mark != "" {
+			label = fmt.Sprintf("%s-%s", mark, team)
+		}
+		fmt.Printf("%s: %s playoffs (D1 %s, D2 %s, D3 %s, WC1 %s, WC2 %s); presidents %s, conference %s, last %s\n", label, format(standings.MadePlayoffs),
+			format(standings.D1Seed), format(standings.D2Seed), format(standings.D3Seed), format(standings.WC1), format(standings.WC2),
+			format(standings.PresidentsTrophy), format(standings.ConferenceLeader), format(standings.LastOverall))
 	}
-
-	return nil
 }
 
-func SimulateSeason(baseElos *map[string]float64, baseSeason *[]NHLGameCSVRow, teams *map[string]NHLTeamJSON) []NHLGameCSVRow {
+// SimulateSeason simulates the unplayed games of the season once. The run
+// index picks the random streams when the inputs have them.
+func SimulateSeason(inputs SimulationInputs, run int) []NHLGameCSVRow {
 	// copy the elo map so we can keep it updated for this simulation
 	seasonElos := make(map[string]float64)
-	for team, elo := range *baseElos {
+	for team, elo := range inputs.Elos {
 		seasonElos[team] = elo
 	}
 
+	simulator := GameSimulator{Model: inputs.EloModel()}
 	seasonGames := []NHLGameCSVRow{}
-	for _, game := range *baseSeason {
+	for _, game := range inputs.Season {
 		if game.Status == "Final" {
 			seasonGames = append(seasonGames, game)
 			continue
 		}
+		if inputs.Streams != nil {
+			simulator.Rand = inputs.Streams.ForGame(run, game.GamePK)
+		}
 
-		seasonGames = append(seasonGames, SimulateGame(game, seasonElos, teams))
+		if inputs.Scenario != nil {
+			seasonGames = append(seasonGames, inputs.Scenario.SimulateGame(simulator, game, seasonElos, &inputs.Teams))
+			continue
+		}
+		seasonGames = append(seasonGames, simulator.SimulateGame(game, seasonElos, &inputs.Teams))
 	}
 
 	return seasonGames
 }
 
-func SimulateGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) NHLGameCSVRow {
-	simulatedGame := game
-	simulatedGame.Status = "Simulated"
-
-	homeElo := elos[game.HomeTeam]
-	if (*teams)[game.HomeTeam].Venue.Name == game.Venue {
-		// 50 points for home ice advantage
-		homeElo += 50
-	}
-	awayElo := elos[game.AwayTeam]
-	eloDiff := homeElo - awayElo
-	/*if game.IsPlayoff == 1 {
-		eloDiff = eloDiff * 1.25
-	}*/
-	homeWinPct := 1.0 / (math.Pow(10, -eloDiff/400.0) + 1)
-
-	//fmt.Printf("%s (elo %f) vs. %s (elo %f): %f\n", game.HomeTeam, homeElo-50, game.AwayTeam, awayElo, homeWinPct)
+func (g GameSimulator) SimulateGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) NHLGameCSVRow {
+	eloDiff, homeWinPct := g.Model.GameWinProbability(game, elos, teams)
 
-	isHomeWin := rand.Float64() < homeWinPct
+	isHomeWin := g.float64() < homeWinPct
 	//fmt.Printf("  simulated home win? %t\n", isHomeWin)
 
-	otChance := 1.0 / (1 + math.Exp(-1.0*(-1.1320032+(-0.0009822*eloDiff))))
+	return g.SimulateGameWithWinner(game, elos, eloDiff, homeWinPct, isHomeWin)
+}
+
+// SimulateGameWithWinner simulates the rest of a game once the winner is known:
+// whether it went to overtime or a shootout and the final score.
+func (g GameSimulator) SimulateGameWithWinner(game NHLGameCSVRow, elos map[string]float64, eloDiff float64, homeWinPct float64, isHomeWin bool) NHLGameCSVRow {
+	otChance := OvertimeProbability(eloDiff)
 	//fmt.Printf("  ot chance: %f\n", otChance)
-	isOT := rand.Float64() < otChance
-	isShootout := isOT && rand.Intn(2) == 0
+	isOT := g.float64() < otChance
+	isShootout := isOT && g.intn(2) == 0
 	//fmt.Printf("  is OT: %t, is shootout %t\n", isOT, isShootout)
 
-	homePoisson := distuv.Poisson{Lambda: 2.8411351 + (0.0042408 * eloDiff)}
-	awayPoisson := distuv.Poisson{Lambda: 2.8411351 + (0.0042408 * -eloDiff)}
+	homeScore, awayScore := g.SimulateScore(eloDiff, isHomeWin, isOT)
+
+	return g.Model.RecordGameResult(game, elos, eloDiff, homeWinPct, homeScore, awayScore, isOT, isShootout)
+}
+
+func OvertimeProbability(eloDiff float64) float64 {
+	return 1.0 / (1 + math.Exp(-1.0*(-1.1320032+(-0.0009822*eloDiff))))
+}
+
+// GoalLambdas are the mean goals the home and away team score before the
+// winner is known, given the elo difference between them.
+func GoalLambdas(eloDiff float64) (float64, float64) {
+	return 2.8411351 + (0.0042408 * eloDiff), 2.8411351 + (0.0042408 * -eloDiff)
+}
+
+// SimulateScore draws a final score where the right team won, and by exactly
+// one goal if the game went to overtime.
+func (g GameSimulator) SimulateScore(eloDiff float64, isHomeWin bool, isOT bool) (int, int) {
+	homeLambda, awayLambda := GoalLambdas(eloDiff)
+	homePoisson := distuv.Poisson{Lambda: homeLambda, Src: g.source()}
+	awayPoisson := distuv.Poisson{Lambda: awayLambda, Src: g.source()}
 	var homeScore, awayScore, goalDiff int
 	attempts := 0
 	for {
@@ -181,6 +450,38 @@ func SimulateGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string
 	}
 	//fmt.Printf("  simulated score: %d - %d (after %d attempts) \n", homeScore, awayScore, attempts)
 
+	return homeScore, awayScore
+}
+
+// MostLikelyScore is the likeliest final score under the same goal model as
+// SimulateScore where the right team won, by exactly one goal in overtime.
+func MostLikelyScore(eloDiff float64, isHomeWin bool, isOT bool) (int, int) {
+	homeLambda, awayLambda := GoalLambdas(eloDiff)
+	homePoisson := distuv.Poisson{Lambda: homeLambda}
+	awayPoisson := distuv.Poisson{Lambda: awayLambda}
+	bestHome, bestAway, bestProb := 0, 0, -1.0
+	for homeScore := 0; homeScore <= maxLikelyGoals; homeScore++ {
+		for awayScore := 0; awayScore <= maxLikelyGoals; awayScore++ {
+			if (homeScore > awayScore) != isHomeWin || homeScore == awayScore {
+				continue
+			}
+			if isOT && homeScore-awayScore != 1 && awayScore-homeScore != 1 {
+				continue
+			}
+			prob := homePoisson.Prob(float64(homeScore)) * awayPoisson.Prob(float64(awayScore))
+			if prob > bestProb {
+				bestHome, bestAway, bestProb = homeScore, awayScore, prob
+			}
+		}
+	}
+	return bestHome, bestAway
+}
+
+// RecordGameResult marks the game as simulated with the given result and
+// shifts both teams' elos accordingly.
+func (m EloModel) RecordGameResult(game NHLGameCSVRow, elos map[string]float64, eloDiff float64, homeWinPct float64, homeScore int, awayScore int, isOT bool, isShootout bool) NHLGameCSVRow {
+	simulatedGame := game
+	simulatedGame.Status = "Simulated"
 	simulatedGame.HomeScore = homeScore
 	simulatedGame.AwayScore = awayScore
 	if isOT {
@@ -190,11 +491,11 @@ func SimulateGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string
 		simulatedGame.IsShootout = 1
 	}
 
-	shift := CalculateEloShift(eloDiff, homeWinPct, &simulatedGame)
+	shift := m.CalculateEloShift(eloDiff, homeWinPct, &simulatedGame)
 
 	//fmt.Printf("  shift: %f (mov: %f, aca: %f, pgf: %f)\n", shift, marginOfVictoryMultiplier, autocorrelationAdjustment, pregameFavoriteMultiplier)
 
-	if isHomeWin {
+	if homeScore > awayScore {
 		elos[game.HomeTeam] += shift
 		elos[game.AwayTeam] -= shift
 	} else {
@@ -207,7 +508,81 @@ func SimulateGame(game NHLGameCSVRow, elos map[string]float64, teams *map[string
 	return simulatedGame
 }
 
+// GameWinProbability returns the elo difference between the home and away
+// team, including home ice advantage, and the resulting home win probability
+// under the default elo model.
+func GameWinProbability(game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) (float64, float64) {
+	return DefaultEloModel.GameWinProbability(game, elos, teams)
+}
+
+func (m EloModel) GameWinProbability(game NHLGameCSVRow, elos map[string]float64, teams *map[string]NHLTeamJSON) (float64, float64) {
+	homeElo := elos[game.HomeTeam]
+	if (*teams)[game.HomeTeam].Venue.Name == game.Venue {
+		homeElo += m.HomeIceFor(game)
+	}
+	awayElo := elos[game.AwayTeam]
+	eloDiff := homeElo - awayElo
+	if game.IsPlayoff == 1 {
+		eloDiff = eloDiff * m.PlayoffMultiplier
+	}
+	homeWinPct := WinProbability(eloDiff)
+
+	//fmt.Printf("%s (elo %f) vs. %s (elo %f): %f\n", game.HomeTeam, homeElo-m.HomeIce, game.AwayTeam, awayElo, homeWinPct)
+
+	return eloDiff, homeWinPct
+}
+
+// WinProbability is the chance the home team wins given the elo difference
+// between it and the away team, including any home ice advantage.
+func WinProbability(eloDiff float64) float64 {
+	return 1.0 / (math.Pow(10, -eloDiff/400.0) + 1)
+}
+
+// GameOutcome is the result of a game from the standings' point of view,
+// with shootouts counted as overtime.
+type GameOutcome int
+
+const (
+	HomeRegulationWin GameOutcome = iota
+	HomeOTWin
+	AwayOTWin
+	AwayRegulationWin
+	numGameOutcomes
+)
+
+func OutcomeOf(game NHLGameCSVRow) GameOutcome {
+	if game.HomeScore > game.AwayScore {
+		if game.IsOT == 1 {
+			return HomeOTWin
+		}
+		return HomeRegulationWin
+	}
+	if game.IsOT == 1 {
+		return AwayOTWin
+	}
+	return AwayRegulationWin
+}
+
+func (o GameOutcome) Describe(game NHLGameCSVRow) string {
+	switch o {
+	case HomeRegulationWin:
+		return fmt.Sprintf("%s regulation win", game.HomeTeam)
+	case HomeOTWin:
+		return fmt.Sprintf("%s OT/SO win", game.HomeTeam)
+	case AwayOTWin:
+		return fmt.Sprintf("%s OT/SO win", game.AwayTeam)
+	default:
+		return fmt.Sprintf("%s regulation win", game.AwayTeam)
+	}
+}
+
+// CalculateEloShift is how far a game moves the elos of both teams under the
+// default elo model.
 func CalculateEloShift(eloDiff float64, homeWinPct float64, game *NHLGameCSVRow) float64 {
+	return DefaultEloModel.CalculateEloShift(eloDiff, homeWinPct, game)
+}
+
+func (m EloModel) CalculateEloShift(eloDiff float64, homeWinPct float64, game *NHLGameCSVRow) float64 {
 	var winnerEloDiff float64
 	var goalDiff int
 	if game.HomeScore > game.AwayScore {
@@ -238,13 +613,14 @@ func CalculateEloShift(eloDiff float64, homeWinPct float64, game *NHLGameCSVRow)
 	}
 	pregameFavoriteMultiplier := teamWin - teamWinProb
 
-	return 6.0 * marginOfVictoryMultiplier * autocorrelationAdjustment * pregameFavoriteMultiplier
+	return m.KFor(*game) * marginOfVictoryMultiplier * autocorrelationAdjustment * pregameFavoriteMultiplier
 }
 
 type NHLSeasonStats struct {
 	Team           string
 	Wins           int
 	Losses         int
+	OTLosses       int
 	RegulationWins int
 	OTWins         int
 	SOWins         int
@@ -253,6 +629,11 @@ type NHLSeasonStats struct {
 	GoalsAgainst   int
 }
 
+// RegulationLosses is the number of losses that did not earn a point.
+func (stats NHLSeasonStats) RegulationLosses() int {
+	return stats.Losses - stats.OTLosses
+}
+
 type GamesWonTiebreakerKey struct {
 	Points int
 	RW     int
@@ -260,18 +641,68 @@ type GamesWonTiebreakerKey struct {
 	SO     int
 }
 
+// Standings are the final standings of a season: the playoff seeds, every
+// team's stats in league order and each team's league, conference and
+// division rank.
 type Standings struct {
 	DivisionSeeds map[string][]string
 	WildCards     map[string][]string
+	League        []*NHLSeasonStats
+	Ranks         map[string]TeamRanks
 }
 
-func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) Standings {
+// TeamRanks are a team's 1-based finishing positions.
+type TeamRanks struct {
+	League     int
+	Conference int
+	Division   int
+}
+
+func (s Standings) PlayoffTeams() map[string]bool {
+	playoffTeams := make(map[string]bool)
+	for _, teams := range s.DivisionSeeds {
+		for _, team := range teams {
+			playoffTeams[team] = true
+		}
+	}
+	for _, teams := range s.WildCards {
+		for _, team := range teams {
+			playoffTeams[team] = true
+		}
+	}
+	return playoffTeams
+}
+
+func (s Standings) MadePlayoffs(team string) bool {
+	for _, teams := range s.DivisionSeeds {
+		for _, t := range teams {
+			if t == team {
+				return true
+			}
+		}
+	}
+	for _, teams := range s.WildCards {
+		for _, t := range teams {
+			if t == team {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CalculateSeasonStats totals each team's record from the Final and simulated
+// games in the season; games not yet played are ignored.
+func CalculateSeasonStats(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) map[string]*NHLSeasonStats {
 	seasonStats := make(map[string]*NHLSeasonStats)
 	for abbr := range *teams {
 		seasonStats[abbr] = &NHLSeasonStats{Team: abbr}
 	}
 
 	for _, game := range *games {
+		if game.Status != "Final" && game.Status != "Simulated" {
+			continue
+		}
 		homeTeamStats := seasonStats[game.HomeTeam]
 		awayTeamStats := seasonStats[game.AwayTeam]
 
@@ -289,9 +720,11 @@ func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) S
 		loser.Losses += 1
 		if game.IsShootout == 1 {
 			winner.SOWins += 1
+			loser.OTLosses += 1
 			loser.Points += 1
 		} else if game.IsOT == 1 {
 			winner.OTWins += 1
+			loser.OTLosses += 1
 			loser.Points += 1
 		} else {
 			winner.RegulationWins += 1
@@ -303,6 +736,12 @@ func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) S
 		awayTeamStats.GoalsAgainst += game.HomeScore
 	}
 
+	return seasonStats
+}
+
+func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) Standings {
+	seasonStats := CalculateSeasonStats(teams, games)
+
 	h2hTiebreakers := make(map[GamesWonTiebreakerKey][]string)
 
 	finalSeasonStats := []*NHLSeasonStats{}
@@ -367,9 +806,19 @@ func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) S
 
 	divisionSeeds := make(map[string][]string)
 	conferenceWildCards := make(map[string][]string)
+	ranks := make(map[string]TeamRanks)
+	conferenceCounts := make(map[string]int)
+	divisionCounts := make(map[string]int)
 
-	for _, teamStat := range finalSeasonStats {
+	for i, teamStat := range finalSeasonStats {
 		team := (*teams)[teamStat.Team]
+		conferenceCounts[team.Conference.Name] += 1
+		divisionCounts[team.Division.Name] += 1
+		ranks[teamStat.Team] = TeamRanks{
+			League:     i + 1,
+			Conference: conferenceCounts[team.Conference.Name],
+			Division:   divisionCounts[team.Division.Name],
+		}
 		ds := divisionSeeds[team.Division.Name]
 		wc := conferenceWildCards[team.Conference.Name]
 		if len(ds) < 3 {
@@ -389,6 +838,8 @@ func CalculateStandings(teams *map[string]NHLTeamJSON, games *[]NHLGameCSVRow) S
 	return Standings{
 		DivisionSeeds: divisionSeeds,
 		WildCards:     conferenceWildCards,
+		League:        finalSeasonStats,
+		Ranks:         ranks,
 	}
 
 }
@@ -401,10 +852,18 @@ func GamesPlayedTiebreak(teams []string, games *[]NHLGameCSVRow) map[string]int
 	for _, team := range teams {
 		teamSet[team] = true
 	}
+	// every tied team needs a rank, even one that hasn't played the others yet
 	teamGames := make(map[string][]NHLGameCSVRow)
+	for _, team := range teams {
+		teamGames[team] = []NHLGameCSVRow{}
+	}
 	gamesByHomeAway := make(map[string]int)
 
 	for _, game := range *games {
+		// unplayed games have no points to hand out yet
+		if game.Status != "Final" && game.Status != "Simulated" {
+			continue
+		}
 		_, homePresent := teamSet[game.HomeTeam]
 		_, awayPresent := teamSet[game.AwayTeam]
 		if homePresent && awayPresent {
@@ -443,7 +902,11 @@ func GamesPlayedTiebreak(teams []string, games *[]NHLGameCSVRow) map[string]int
 			}
 		}
 
-		pctWon := float64(ptsWon) / float64(ptsAvailable)
+		// without games between them the tiebreak can't separate the teams
+		pctWon := 0.5
+		if ptsAvailable > 0 {
+			pctWon = float64(ptsWon) / float64(ptsAvailable)
+		}
 		if len(teams) > 2 {
 			fmt.Printf(" team %s won %d out of %d points (%f%%)\n", team, ptsWon, ptsAvailable, pctWon*100)
 		}
@@ -469,3 +932,35 @@ func GamesPlayedTiebreak(teams []string, games *[]NHLGameCSVRow) map[string]int
 
 	return teamRanks
 }
+
+// goalTiebreakTies returns, for every team, the teams it is still level with
+// after points, regulation, overtime and shootout wins and the games-played
+// tiebreak, so that goals decide between them.
+func goalTiebreakTies(standings Standings, season *[]NHLGameCSVRow) map[string][]string {
+	groups := make(map[GamesWonTiebreakerKey][]string)
+	for _, stats := range standings.League {
+		key := GamesWonTiebreakerKey{
+			Points: stats.Points,
+			RW:     stats.RegulationWins,
+			OT:     stats.OTWins,
+			SO:     stats.SOWins,
+		}
+		groups[key] = append(groups[key], stats.Team)
+	}
+
+	ties := make(map[string][]string)
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		ranks := GamesPlayedTiebreak(group, season)
+		for _, team := range group {
+			for _, other := range group {
+				if team != other && ranks[team] == ranks[other] {
+					ties[team] = append(ties[team], other)
+				}
+			}
+		}
+	}
+	return ties
+}
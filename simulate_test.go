@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestGamesPlayedTiebreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		games []NHLGameCSVRow
+		// whether A1 ranks ahead of, level with or behind A2
+		want int
+	}{
+		{
+			name:  "no games between them",
+			games: []NHLGameCSVRow{{Status: "Final", HomeTeam: "A1", AwayTeam: "M1", HomeScore: 3, AwayScore: 1}},
+			want:  0,
+		},
+		{
+			name:  "only unplayed games between them",
+			games: []NHLGameCSVRow{{Status: "Preview", HomeTeam: "A1", AwayTeam: "A2"}},
+			want:  0,
+		},
+		{
+			name: "won the played game",
+			games: []NHLGameCSVRow{
+				{Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 1},
+				{Status: "Final", HomeTeam: "A2", AwayTeam: "A1", HomeScore: 2, AwayScore: 4},
+				{Status: "Preview", HomeTeam: "A2", AwayTeam: "A1"},
+			},
+			want: 1,
+		},
+		{
+			name: "lost in overtime",
+			games: []NHLGameCSVRow{
+				{Status: "Simulated", HomeTeam: "A2", AwayTeam: "A1", HomeScore: 2, AwayScore: 1, IsOT: 1},
+				{Status: "Simulated", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 1, AwayScore: 2},
+			},
+			want: -1,
+		},
+		{
+			name: "the extra home game of an odd series is skipped",
+			games: []NHLGameCSVRow{
+				{Status: "Final", HomeTeam: "A1", AwayTeam: "A2", HomeScore: 3, AwayScore: 1},
+			},
+			want: 0,
+		},
+	}
+	for _, test := range tests {
+		ranks := GamesPlayedTiebreak([]string{"A1", "A2"}, &test.games)
+		first, ok1 := ranks["A1"]
+		second, ok2 := ranks["A2"]
+		if !ok1 || !ok2 {
+			t.Errorf("%s: missing ranks %v", test.name, ranks)
+			continue
+		}
+		got := 0
+		if first > second {
+			got = 1
+		} else if first < second {
+			got = -1
+		}
+		if got != test.want {
+			t.Errorf("%s: ranks %v", test.name, ranks)
+		}
+	}
+}
+
+func TestTallyWeightedStandings(t *testing.T) {
+	teams := longShotTeams()
+	// M4 beats A5 and M1 beats A1 on the last night, leaving M1 alone on top,
+	// M5 with no points at the bottom and W1 the only team in the West
+	season := lastNightSeason()
+	season[len(season)-2] = NHLGameCSVRow{Status: "Final", HomeTeam: "A5", AwayTeam: "M4", HomeScore: 1, AwayScore: 2}
+	season[len(season)-1] = NHLGameCSVRow{Status: "Final", HomeTeam: "A1", AwayTeam: "M1", HomeScore: 1, AwayScore: 2}
+	standings := CalculateStandings(&teams, &season)
+
+	results := NewTeamSimulationResults(teams)
+	TallyWeightedStandings(results, standings, 0.25)
+	TallyStandings(results, standings)
+
+	for team, result := range results {
+		want := TeamSimulationResults{}
+		switch team {
+		case "M1":
+			want.PresidentsTrophy, want.ConferenceLeader = 1.25, 1.25
+		case "W1":
+			want.ConferenceLeader = 1.25
+		case "M5":
+			want.LastOverall = 1.25
+		}
+		got := TeamSimulationResults{PresidentsTrophy: result.PresidentsTrophy, ConferenceLeader: result.ConferenceLeader, LastOverall: result.LastOverall}
+		if got != want {
+			t.Errorf("%s: presidents %f, conference %f, last %f; want %+v", team, got.PresidentsTrophy, got.ConferenceLeader, got.LastOverall, want)
+		}
+	}
+	if made := results["A5"].MadePlayoffs; made != 0 {
+		t.Errorf("A5 made the playoffs %f times", made)
+	}
+	if made := results["M4"].MadePlayoffs; made != 1.25 {
+		t.Errorf("M4 made the playoffs %f times, want 1.25", made)
+	}
+}
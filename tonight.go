@@ -0,0 +1,438 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClinchEvent is something a team can clinch, or be eliminated from, on a
+// given night.
+type ClinchEvent int
+
+const (
+	ClinchesPlayoffs ClinchEvent = iota
+	ClinchesDivision
+	ClinchesConference
+	ClinchesPresidents
+	IsEliminated
+	numClinchEvents
+)
+
+func (e ClinchEvent) Describe() string {
+	switch e {
+	case ClinchesPlayoffs:
+		return "clinches a playoff spot"
+	case ClinchesDivision:
+		return "clinches the division"
+	case ClinchesConference:
+		return "clinches the conference"
+	case ClinchesPresidents:
+		return "clinches the Presidents' Trophy"
+	default:
+		return "is eliminated"
+	}
+}
+
+// OutcomeMask is a set of possible outcomes of a game, one bit per GameOutcome.
+type OutcomeMask uint8
+
+const allOutcomes = OutcomeMask(1<<numGameOutcomes - 1)
+
+func (m OutcomeMask) Describe(game NHLGameCSVRow) string {
+	home, away := game.HomeTeam, game.AwayTeam
+	switch m {
+	case 1 << HomeRegulationWin:
+		return fmt.Sprintf("%s beats %s in regulation", home, away)
+	case 1 << HomeOTWin:
+		return fmt.Sprintf("%s beats %s in OT/SO", home, away)
+	case 1 << AwayOTWin:
+		return fmt.Sprintf("%s beats %s in OT/SO", away, home)
+	case 1 << AwayRegulationWin:
+		return fmt.Sprintf("%s beats %s in regulation", away, home)
+	case 1<<HomeRegulationWin | 1<<HomeOTWin:
+		return fmt.Sprintf("%s beats %s", home, away)
+	case 1<<AwayRegulationWin | 1<<AwayOTWin:
+		return fmt.Sprintf("%s beats %s", away, home)
+	case allOutcomes &^ (1 << AwayRegulationWin):
+		return fmt.Sprintf("%s gets a point against %s", home, away)
+	case allOutcomes &^ (1 << HomeRegulationWin):
+		return fmt.Sprintf("%s gets a point against %s", away, home)
+	case 1<<HomeOTWin | 1<<AwayOTWin:
+		return fmt.Sprintf("%s at %s goes to OT", away, home)
+	case 1<<HomeRegulationWin | 1<<AwayRegulationWin:
+		return fmt.Sprintf("%s at %s ends in regulation", away, home)
+	}
+	outcomes := []string{}
+	for outcome := GameOutcome(0); outcome < numGameOutcomes; outcome++ {
+		if m&(1<<outcome) != 0 {
+			outcomes = append(outcomes, outcome.Describe(game))
+		}
+	}
+	return fmt.Sprintf("%s at %s is a %s", away, home, strings.Join(outcomes, " or "))
+}
+
+// ClinchCondition is a set of results of the night's games, one mask per game,
+// that all lead to the same event.
+type ClinchCondition []OutcomeMask
+
+func (c ClinchCondition) Describe(games []NHLGameCSVRow) string {
+	parts := []string{}
+	for i, mask := range c {
+		if mask != allOutcomes {
+			parts = append(parts, mask.Describe(games[i]))
+		}
+	}
+	if len(parts) == 0 {
+		return "no matter what"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// ClinchScenario is every way a team can clinch or be eliminated from
+// something on the night, given the games that can affect it. On the last
+// night MarginConditions are the results where it comes down to goal
+// differential, so it depends on the margins of the games.
+type ClinchScenario struct {
+	Team             string
+	Event            ClinchEvent
+	Games            []NHLGameCSVRow
+	Conditions       []ClinchCondition
+	MarginConditions []ClinchCondition
+}
+
+// withResult marks a game Final with the outcome, by one goal. The margin only
+// matters to goal differential tiebreaks, which goalTiebreakTies finds so
+// they aren't decided by the made-up score.
+func withResult(game NHLGameCSVRow, outcome GameOutcome) NHLGameCSVRow {
+	game.Status = "Final"
+	game.IsOT = 0
+	game.IsShootout = 0
+	if outcome == HomeOTWin || outcome == AwayOTWin {
+		game.IsOT = 1
+	}
+	if outcome == HomeRegulationWin || outcome == HomeOTWin {
+		game.HomeScore, game.AwayScore = 2, 1
+	} else {
+		game.HomeScore, game.AwayScore = 1, 2
+	}
+	return game
+}
+
+// withOutcome returns the records after the game ends with the outcome.
+func withOutcome(records []ClinchRecord, game NHLGameCSVRow, outcome GameOutcome) []ClinchRecord {
+	updated := make([]ClinchRecord, len(records))
+	copy(updated, records)
+	for i := range updated {
+		record := &updated[i]
+		if record.Team != game.HomeTeam && record.Team != game.AwayTeam {
+			continue
+		}
+		isHome := record.Team == game.HomeTeam
+		switch {
+		case (outcome == HomeRegulationWin && isHome) || (outcome == AwayRegulationWin && !isHome):
+			record.Points += 2
+			record.RegulationWins += 1
+		case (outcome == HomeOTWin && isHome) || (outcome == AwayOTWin && !isHome):
+			record.Points += 2
+		case outcome == HomeOTWin || outcome == AwayOTWin:
+			record.Points += 1
+		}
+		record.GamesRemaining -= 1
+		record.MaxPoints = record.Points + 2*record.GamesRemaining
+		record.MaxRegulationWins = record.RegulationWins + record.GamesRemaining
+	}
+	return updated
+}
+
+// ClinchEvents works out from the clinch math which events hold for a team.
+func ClinchEvents(team string, records []ClinchRecord) [numClinchEvents]bool {
+	var record ClinchRecord
+	for _, r := range records {
+		if r.Team == team {
+			record = r
+		}
+	}
+
+	events := [numClinchEvents]bool{}
+	events[ClinchesPlayoffs] = clinchedPlayoffs(record, records)
+	events[ClinchesDivision] = clinchedFirst(record, records, func(rival ClinchRecord) bool { return rival.Division == record.Division })
+	events[ClinchesConference] = clinchedFirst(record, records, func(rival ClinchRecord) bool { return rival.Conference == record.Conference })
+	events[ClinchesPresidents] = clinchedFirst(record, records, func(rival ClinchRecord) bool { return true })
+	events[IsEliminated] = eliminatedFromPlayoffs(record, records)
+	return events
+}
+
+// relevantGames returns the indexes of the night's games that can change the
+// team's status: the ones involving the team or a rival that can still
+// finish either side of it. Teams in the other conference only count while
+// the Presidents' Trophy is still undecided for the team.
+func relevantGames(team ClinchRecord, records []ClinchRecord, games []NHLGameCSVRow) []int {
+	anyTeam := func(rival ClinchRecord) bool { return true }
+	presidentsLive := !clinchedFirst(team, records, anyTeam) && !eliminatedFromFirst(team, records, anyTeam)
+
+	live := map[string]bool{team.Team: true}
+	for _, rival := range records {
+		if rival.Conference != team.Conference && !presidentsLive {
+			continue
+		}
+		if canFinishAhead(rival, team) && !isSurelyAhead(rival, team) {
+			live[rival.Team] = true
+		}
+	}
+	indexes := []int{}
+	for i, game := range games {
+		if live[game.HomeTeam] || live[game.AwayTeam] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// dependsOnMargin is whether the event comes down to goals for the team: it is
+// level through every other tiebreak with teams it is competing with, and
+// the order goals put them in decides the event. Ties between other teams
+// don't matter, since they don't change which teams finish ahead of this one.
+func dependsOnMargin(event ClinchEvent, team string, tiedWith []string, standings Standings, teams map[string]NHLTeamJSON) bool {
+	block := []string{team}
+	for _, other := range tiedWith {
+		switch {
+		case event == ClinchesPresidents,
+			event == ClinchesDivision && teams[other].Division.Name == teams[team].Division.Name,
+			event != ClinchesDivision && teams[other].Conference.Name == teams[team].Conference.Name:
+			block = append(block, other)
+		}
+	}
+	if len(block) == 1 {
+		return false
+	}
+
+	madePlayoffs := 0
+	for _, t := range block {
+		ranks := standings.Ranks[t]
+		switch event {
+		case ClinchesDivision:
+			if ranks.Division == 1 {
+				return true
+			}
+		case ClinchesConference:
+			if ranks.Conference == 1 {
+				return true
+			}
+		case ClinchesPresidents:
+			if ranks.League == 1 {
+				return true
+			}
+		default:
+			if standings.MadePlayoffs(t) {
+				madePlayoffs += 1
+			}
+		}
+	}
+	return madePlayoffs > 0 && madePlayoffs < len(block)
+}
+
+// EnumerateClinchScenarios tries every combination of outcomes of the games
+// relevant to the team and returns the events each combination leads to,
+// skipping events the team has already settled. The night's other games
+// can't change which teams finish ahead of it, so they are resolved as home
+// regulation wins to leave nothing from the night unplayed.
+//
+// On the last night of the season the real standings and tiebreaks from
+// CalculateStandings decide every event, and results where the team is only
+// separated from a rival by goals are reported as depending on the margins.
+// Before then the clinch math decides, so like the clinch command's marks a
+// team may clinch tonight without it showing up until a later night.
+func EnumerateClinchScenarios(team string, teams map[string]NHLTeamJSON, season []NHLGameCSVRow, tonight []int, gameIndexes []int, lastNight bool) []ClinchScenario {
+	records := ClinchRecords(teams, season)
+	before := ClinchEvents(team, records)
+	if before[IsEliminated] {
+		return nil
+	}
+
+	games := []NHLGameCSVRow{}
+	relevant := make(map[int]bool)
+	for _, i := range gameIndexes {
+		games = append(games, season[i])
+		relevant[i] = true
+	}
+
+	simulatedSeason := make([]NHLGameCSVRow, len(season))
+	copy(simulatedSeason, season)
+	for _, i := range tonight {
+		if !relevant[i] {
+			simulatedSeason[i] = withResult(season[i], HomeRegulationWin)
+			records = withOutcome(records, season[i], HomeRegulationWin)
+		}
+	}
+
+	combinations := [numClinchEvents][]ClinchCondition{}
+	marginCombinations := [numClinchEvents][]ClinchCondition{}
+	outcomes := make([]GameOutcome, len(gameIndexes))
+	var enumerate func(depth int, records []ClinchRecord)
+	enumerate = func(depth int, records []ClinchRecord) {
+		if depth == len(gameIndexes) {
+			after := ClinchEvents(team, records)
+			margin := [numClinchEvents]bool{}
+			if lastNight {
+				standings := CalculateStandings(&teams, &simulatedSeason)
+				after[ClinchesPlayoffs] = standings.MadePlayoffs(team)
+				after[ClinchesDivision] = standings.Ranks[team].Division == 1
+				after[ClinchesConference] = standings.Ranks[team].Conference == 1
+				after[ClinchesPresidents] = standings.Ranks[team].League == 1
+				after[IsEliminated] = !after[ClinchesPlayoffs]
+				tiedWith := goalTiebreakTies(standings, &simulatedSeason)[team]
+				for event := ClinchEvent(0); event < numClinchEvents; event++ {
+					margin[event] = dependsOnMargin(event, team, tiedWith, standings, teams)
+				}
+			}
+			condition := make(ClinchCondition, len(outcomes))
+			for i, outcome := range outcomes {
+				condition[i] = 1 << outcome
+			}
+			for event := ClinchEvent(0); event < numClinchEvents; event++ {
+				if before[event] {
+					continue
+				}
+				if margin[event] {
+					marginCombinations[event] = append(marginCombinations[event], condition)
+				} else if after[event] {
+					combinations[event] = append(combinations[event], condition)
+				}
+			}
+			return
+		}
+		i := gameIndexes[depth]
+		for outcome := GameOutcome(0); outcome < numGameOutcomes; outcome++ {
+			outcomes[depth] = outcome
+			simulatedSeason[i] = withResult(season[i], outcome)
+			enumerate(depth+1, withOutcome(records, season[i], outcome))
+		}
+		simulatedSeason[i] = season[i]
+	}
+	enumerate(0, records)
+
+	scenarios := []ClinchScenario{}
+	for event := ClinchEvent(0); event < numClinchEvents; event++ {
+		if len(combinations[event]) == 0 && len(marginCombinations[event]) == 0 {
+			continue
+		}
+		scenarios = append(scenarios, ClinchScenario{
+			Team:             team,
+			Event:            event,
+			Games:            games,
+			Conditions:       MergeConditions(combinations[event]),
+			MarginConditions: MergeConditions(marginCombinations[event]),
+		})
+	}
+	return scenarios
+}
+
+// MergeConditions repeatedly folds together conditions that only differ in
+// one game until nothing else can be merged, so "BOS regulation win" and
+// "BOS OT/SO win" with the same other results become "BOS win".
+func MergeConditions(conditions []ClinchCondition) []ClinchCondition {
+	if len(conditions) == 0 {
+		return conditions
+	}
+	numGames := len(conditions[0])
+	for {
+		merged := false
+		for game := 0; game < numGames; game++ {
+			keys := []string{}
+			byKey := make(map[string]ClinchCondition)
+			for _, condition := range conditions {
+				key := make([]byte, numGames)
+				for i, mask := range condition {
+					key[i] = byte(mask)
+				}
+				key[game] = 0
+				existing, ok := byKey[string(key)]
+				if !ok {
+					keys = append(keys, string(key))
+					byKey[string(key)] = append(ClinchCondition{}, condition...)
+					continue
+				}
+				existing[game] |= condition[game]
+				merged = true
+			}
+			conditions = []ClinchCondition{}
+			for _, key := range keys {
+				conditions = append(conditions, byKey[key])
+			}
+		}
+		if !merged {
+			return conditions
+		}
+	}
+}
+
+func RunTonight(date string, maxGames int) error {
+	season, err := LoadNHLSeason()
+	if err != nil {
+		return err
+	}
+	teams, err := GetNHLTeams()
+	if err != nil {
+		return err
+	}
+
+	tonight := []int{}
+	tonightGames := []NHLGameCSVRow{}
+	for i, game := range season {
+		if game.Date == date && game.Status != "Final" {
+			tonight = append(tonight, i)
+			tonightGames = append(tonightGames, game)
+		}
+	}
+	if len(tonight) == 0 {
+		return fmt.Errorf("no unplayed games on %s", date)
+	}
+
+	lastNight := true
+	for _, game := range season {
+		if game.Status != "Final" && game.Date != date {
+			lastNight = false
+		}
+	}
+
+	records := ClinchRecords(teams, season)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Team < records[j].Team
+	})
+
+	fmt.Printf("clinching scenarios for %s:\n", date)
+	for _, record := range records {
+		relevant := relevantGames(record, records, tonightGames)
+		if len(relevant) == 0 {
+			continue
+		}
+		if len(relevant) > maxGames {
+			fmt.Printf("%s: %d games tonight can affect them, more than --max-games %d\n", record.Team, len(relevant), maxGames)
+			continue
+		}
+		gameIndexes := []int{}
+		for _, i := range relevant {
+			gameIndexes = append(gameIndexes, tonight[i])
+		}
+
+		for _, scenario := range EnumerateClinchScenarios(record.Team, teams, season, tonight, gameIndexes, lastNight) {
+			if len(scenario.Conditions) > 0 {
+				fmt.Printf("%s %s tonight if:\n", scenario.Team, scenario.Event.Describe())
+				for _, condition := range scenario.Conditions {
+					fmt.Printf("  - %s\n", condition.Describe(scenario.Games))
+				}
+			}
+			if len(scenario.MarginConditions) > 0 {
+				fmt.Printf("%s %s tonight depending on the margins if:\n", scenario.Team, scenario.Event.Describe())
+				for _, condition := range scenario.MarginConditions {
+					fmt.Printf("  - %s\n", condition.Describe(scenario.Games))
+				}
+			}
+		}
+	}
+	if !lastNight {
+		fmt.Print("before the last night scenarios come from the clinch command's conservative marks, so a team can clinch tonight without being listed\n")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func outcomeMask(outcomes ...GameOutcome) OutcomeMask {
+	var mask OutcomeMask
+	for _, outcome := range outcomes {
+		mask |= 1 << outcome
+	}
+	return mask
+}
+
+func TestMergeConditions(t *testing.T) {
+	homeWin := outcomeMask(HomeRegulationWin, HomeOTWin)
+	allConditions := []ClinchCondition{}
+	for first := GameOutcome(0); first < numGameOutcomes; first++ {
+		for second := GameOutcome(0); second < numGameOutcomes; second++ {
+			allConditions = append(allConditions, ClinchCondition{outcomeMask(first), outcomeMask(second)})
+		}
+	}
+
+	tests := []struct {
+		name       string
+		conditions []ClinchCondition
+		want       []ClinchCondition
+	}{
+		{
+			name:       "nothing to merge",
+			conditions: []ClinchCondition{},
+			want:       []ClinchCondition{},
+		},
+		{
+			name: "regulation and OT wins make a win",
+			conditions: []ClinchCondition{
+				{outcomeMask(HomeRegulationWin), outcomeMask(AwayRegulationWin)},
+				{outcomeMask(HomeOTWin), outcomeMask(AwayRegulationWin)},
+			},
+			want: []ClinchCondition{{homeWin, outcomeMask(AwayRegulationWin)}},
+		},
+		{
+			name: "different in two games",
+			conditions: []ClinchCondition{
+				{outcomeMask(HomeRegulationWin), outcomeMask(HomeRegulationWin)},
+				{outcomeMask(HomeOTWin), outcomeMask(AwayRegulationWin)},
+			},
+			want: []ClinchCondition{
+				{outcomeMask(HomeRegulationWin), outcomeMask(HomeRegulationWin)},
+				{outcomeMask(HomeOTWin), outcomeMask(AwayRegulationWin)},
+			},
+		},
+		{
+			name: "merges across games",
+			conditions: []ClinchCondition{
+				{outcomeMask(HomeRegulationWin), outcomeMask(HomeRegulationWin)},
+				{outcomeMask(HomeRegulationWin), outcomeMask(HomeOTWin)},
+				{outcomeMask(HomeOTWin), outcomeMask(HomeRegulationWin)},
+				{outcomeMask(HomeOTWin), outcomeMask(HomeOTWin)},
+			},
+			want: []ClinchCondition{{homeWin, homeWin}},
+		},
+		{
+			name:       "every result",
+			conditions: allConditions,
+			want:       []ClinchCondition{{allOutcomes, allOutcomes}},
+		},
+	}
+	for _, test := range tests {
+		if got := MergeConditions(test.conditions); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: merged to %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClinchConditionDescribe(t *testing.T) {
+	games := []NHLGameCSVRow{
+		{HomeTeam: "BOS", AwayTeam: "TOR"},
+		{HomeTeam: "MTL", AwayTeam: "OTT"},
+	}
+	tests := []struct {
+		condition ClinchCondition
+		want      string
+	}{
+		{ClinchCondition{allOutcomes, allOutcomes}, "no matter what"},
+		{ClinchCondition{outcomeMask(HomeRegulationWin, HomeOTWin), allOutcomes}, "BOS beats TOR"},
+		{ClinchCondition{allOutcomes, allOutcomes &^ outcomeMask(HomeRegulationWin)}, "OTT gets a point against MTL"},
+		{ClinchCondition{outcomeMask(AwayOTWin), outcomeMask(HomeOTWin, AwayOTWin)}, "TOR beats BOS in OT/SO and OTT at MTL goes to OT"},
+	}
+	for _, test := range tests {
+		if got := test.condition.Describe(games); got != test.want {
+			t.Errorf("%v: %q, want %q", test.condition, got, test.want)
+		}
+	}
+}
+
+// lastNightSeason is a finished test conference season where A4, A5 and M4
+// race for the two wild cards. A4 is done with 4 points and A5 and M4 play
+// each other on the last night, along with A1 and M1.
+func lastNightSeason() []NHLGameCSVRow {
+	season := []NHLGameCSVRow{}
+	beatM5 := func(team string, times int) {
+		for i := 0; i < times; i++ {
+			season = append(season, NHLGameCSVRow{Date: "2023-04-01", Status: "Final", HomeTeam: team, AwayTeam: "M5", HomeScore: 3, AwayScore: 0})
+		}
+	}
+	for _, team := range []string{"A1", "A2", "A3", "M1", "M2", "M3"} {
+		beatM5(team, 5)
+	}
+	beatM5("A4", 2)
+	beatM5("M4", 2)
+	beatM5("A5", 1)
+	return append(season,
+		NHLGameCSVRow{Date: "2023-04-02", Status: "Preview", HomeTeam: "A5", AwayTeam: "M4"},
+		NHLGameCSVRow{Date: "2023-04-02", Status: "Preview", HomeTeam: "A1", AwayTeam: "M1"},
+	)
+}
+
+func TestEnumerateClinchScenariosLastNight(t *testing.T) {
+	teams := testConferenceTeams()
+	season := lastNightSeason()
+	tonight := []int{len(season) - 2, len(season) - 1}
+
+	scenarios := EnumerateClinchScenarios("A4", teams, season, tonight, tonight[:1], true)
+	byEvent := make(map[ClinchEvent]ClinchScenario)
+	for _, scenario := range scenarios {
+		byEvent[scenario.Event] = scenario
+	}
+	if len(byEvent) != 2 {
+		t.Fatalf("scenarios for %v, want playoffs and elimination only", byEvent)
+	}
+
+	// any point for M4 leaves A5 behind A4, while an A5 regulation win ties all
+	// three on points and regulation wins with no games between A4 and the
+	// others, so goals decide it
+	clinches := byEvent[ClinchesPlayoffs]
+	want := []ClinchCondition{{allOutcomes &^ outcomeMask(HomeRegulationWin)}}
+	if !reflect.DeepEqual(clinches.Conditions, want) {
+		t.Errorf("clinch conditions %v, want %v", clinches.Conditions, want)
+	}
+	want = []ClinchCondition{{outcomeMask(HomeRegulationWin)}}
+	if !reflect.DeepEqual(clinches.MarginConditions, want) {
+		t.Errorf("clinch margin conditions %v, want %v", clinches.MarginConditions, want)
+	}
+	if got := clinches.Conditions[0].Describe(clinches.Games); got != "M4 gets a point against A5" {
+		t.Errorf("clinch condition described as %q", got)
+	}
+
+	eliminated := byEvent[IsEliminated]
+	if len(eliminated.Conditions) != 0 || !reflect.DeepEqual(eliminated.MarginConditions, want) {
+		t.Errorf("elimination conditions %v and margin conditions %v, want none and %v", eliminated.Conditions, eliminated.MarginConditions, want)
+	}
+}
+
+func TestEnumerateClinchScenariosFirstPlace(t *testing.T) {
+	teams := testConferenceTeams()
+	season := lastNightSeason()
+	tonight := []int{len(season) - 2, len(season) - 1}
+
+	// M1 is tied with five teams on 10 points, so it takes the conference by
+	// winning at A1 in any way. A5 and M4 can't reach it, so their game is only
+	// played out for the final standings.
+	scenarios := EnumerateClinchScenarios("M1", teams, season, tonight, tonight[1:], true)
+	want := []ClinchCondition{{outcomeMask(AwayRegulationWin, AwayOTWin)}}
+	found := 0
+	for _, scenario := range scenarios {
+		if scenario.Event != ClinchesConference && scenario.Event != ClinchesPresidents {
+			continue
+		}
+		found++
+		if !reflect.DeepEqual(scenario.Conditions, want) || len(scenario.MarginConditions) != 0 {
+			t.Errorf("%s: conditions %v and margin conditions %v, want %v", scenario.Event.Describe(), scenario.Conditions, scenario.MarginConditions, want)
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d of the conference and Presidents' Trophy scenarios", found)
+	}
+}